@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/taiidani/no-time-to-explain/internal/config"
+	"github.com/taiidani/no-time-to-explain/internal/db/models"
+	"github.com/taiidani/no-time-to-explain/internal/triggers"
+)
+
+// runCommand runs the subcommand named by the arguments, such as
+// "export -format json", instead of starting the bot. It returns the exit code
+// of the process, leaving the caller to exit once it has cleaned up.
+func runCommand(ctx context.Context, conn *sql.DB, args []string) int {
+	var err error
+	switch args[0] {
+	case "export":
+		err = exportCommand(ctx, conn, args[1:])
+	case "import":
+		err = importCommand(ctx, conn, args[1:])
+	default:
+		err = fmt.Errorf("unknown command %q, expected export or import", args[0])
+	}
+
+	if err != nil {
+		slog.ErrorContext(ctx, "command failed", "err", err)
+		return 1
+	}
+	return 0
+}
+
+// exportCommand writes every message and feed as a configuration document.
+func exportCommand(ctx context.Context, conn *sql.DB, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", config.FormatYAML, "Format of the document, yaml or json")
+	output := flags.String("o", "", "File to write the document to, defaulting to stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	doc, err := config.Export(ctx, models.New(conn))
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return config.Encode(w, doc, *format)
+}
+
+// importCommand upserts the messages and feeds of a configuration document,
+// printing the changes that it made.
+func importCommand(ctx context.Context, conn *sql.DB, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "Print the changes without making them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: import [-dry-run] <file>")
+	}
+
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	doc, err := config.Decode(f)
+	if err != nil {
+		return err
+	}
+
+	diff, err := config.Import(ctx, conn, doc, *dryRun)
+	for _, change := range diff.Changes {
+		if change.Action == config.ActionUnchanged {
+			continue
+		}
+		fmt.Printf("%s %s %q %v\n", change.Action, change.Kind, change.Key, change.Fields)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		fmt.Println("Dry run, nothing was changed")
+	}
+	fmt.Printf("%d created, %d updated, %d unchanged, %d untracked\n",
+		diff.Count(config.ActionCreate),
+		diff.Count(config.ActionUpdate),
+		diff.Count(config.ActionUnchanged),
+		diff.Count(config.ActionUntracked),
+	)
+	if !*dryRun && diff.Count(config.ActionCreate)+diff.Count(config.ActionUpdate) > 0 {
+		// Only the admin page can tell a running bot to reload its messages
+		fmt.Printf("A running bot picks up the imported messages within %.0f minutes\n", triggers.MaxAge.Minutes())
+	}
+	return nil
+}
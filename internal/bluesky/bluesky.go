@@ -22,6 +22,16 @@ func NewBlueskyClient() *BlueskyClient {
 	}
 }
 
+// APIError is returned when the API responds with an unsuccessful status.
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Body)
+}
+
 // UserData contains basic identifying information about the given user.
 type UserData struct {
 	DID         string `json:"did"`
@@ -46,7 +56,7 @@ func (c *BlueskyClient) GetUser(handle string) (*UserData, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error: %s", string(bodyBytes))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
 	}
 
 	var userData UserData
@@ -0,0 +1,206 @@
+package bluesky
+
+import (
+	"time"
+)
+
+// Embed types as reported in the "$type" field of a post's hydrated embed view.
+const (
+	EmbedImagesView          = "app.bsky.embed.images#view"
+	EmbedExternalView        = "app.bsky.embed.external#view"
+	EmbedRecordView          = "app.bsky.embed.record#view"
+	EmbedRecordWithMediaView = "app.bsky.embed.recordWithMedia#view"
+	EmbedVideoView           = "app.bsky.embed.video#view"
+)
+
+// Embed types as reported in the "$type" field of a post record's raw embed.
+const (
+	EmbedImages          = "app.bsky.embed.images"
+	EmbedExternal        = "app.bsky.embed.external"
+	EmbedRecord          = "app.bsky.embed.record"
+	EmbedRecordWithMedia = "app.bsky.embed.recordWithMedia"
+	EmbedVideo           = "app.bsky.embed.video"
+)
+
+// Facet feature types as reported in the "$type" field of a `FacetFeature`.
+const (
+	FacetLink    = "app.bsky.richtext.facet#link"
+	FacetMention = "app.bsky.richtext.facet#mention"
+	FacetTag     = "app.bsky.richtext.facet#tag"
+)
+
+// PostEmbedView is the hydrated form of a post's embed, as returned alongside a
+// `FeedPost`. Only the fields relevant to the reported Type are populated.
+type PostEmbedView struct {
+	Type string `json:"$type"`
+
+	// Populated for app.bsky.embed.images#view
+	Images []EmbedImageView `json:"images,omitempty"`
+
+	// Populated for app.bsky.embed.external#view
+	External *EmbedExternalViewData `json:"external,omitempty"`
+
+	// Populated for app.bsky.embed.record#view and
+	// app.bsky.embed.recordWithMedia#view. See `Quoted`.
+	Record *EmbedRecordViewData `json:"record,omitempty"`
+
+	// Populated for app.bsky.embed.recordWithMedia#view
+	Media *PostEmbedView `json:"media,omitempty"`
+
+	// Populated for app.bsky.embed.video#view
+	Playlist  string `json:"playlist,omitempty"`
+	Thumbnail string `json:"thumbnail,omitempty"`
+	Alt       string `json:"alt,omitempty"`
+}
+
+// EmbedImageView is a single image attached to a post.
+type EmbedImageView struct {
+	Thumb    string `json:"thumb"`
+	Fullsize string `json:"fullsize"`
+	Alt      string `json:"alt"`
+}
+
+// EmbedExternalViewData is the link card rendered for an external URL.
+type EmbedExternalViewData struct {
+	URI         string `json:"uri"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Thumb       string `json:"thumb,omitempty"`
+}
+
+// EmbedRecordViewData is a post referenced by a quote post.
+//
+// For app.bsky.embed.recordWithMedia#view the quoted post is wrapped in one
+// more level of Record, which `PostEmbedView.Quoted` unwraps.
+type EmbedRecordViewData struct {
+	Type      string               `json:"$type"`
+	URI       string               `json:"uri"`
+	CID       string               `json:"cid"`
+	Author    FeedPostAuthor       `json:"author"`
+	Value     *FeedPostRecord      `json:"value,omitempty"`
+	IndexedAt time.Time            `json:"indexedAt"`
+	Record    *EmbedRecordViewData `json:"record,omitempty"`
+}
+
+// Quoted returns the post quoted by this embed, if any.
+func (e *PostEmbedView) Quoted() *EmbedRecordViewData {
+	if e == nil || e.Record == nil {
+		return nil
+	}
+
+	switch e.Type {
+	case EmbedRecordView:
+		return e.Record
+	case EmbedRecordWithMediaView:
+		return e.Record.Record
+	}
+
+	return nil
+}
+
+// Attachment returns the media attached to this embed, if any. For quote posts
+// with media this is the media half of the embed.
+func (e *PostEmbedView) Attachment() *PostEmbedView {
+	if e == nil {
+		return nil
+	}
+
+	switch e.Type {
+	case EmbedImagesView, EmbedExternalView, EmbedVideoView:
+		return e
+	case EmbedRecordWithMediaView:
+		return e.Media.Attachment()
+	}
+
+	return nil
+}
+
+// PostEmbed is the raw form of a post's embed, as stored in its `FeedPostRecord`.
+// Only the fields relevant to the reported Type are populated.
+type PostEmbed struct {
+	Type string `json:"$type"`
+
+	// Populated for app.bsky.embed.images
+	Images []EmbedImage `json:"images,omitempty"`
+
+	// Populated for app.bsky.embed.external
+	External *EmbedExternalData `json:"external,omitempty"`
+
+	// Populated for app.bsky.embed.record and app.bsky.embed.recordWithMedia.
+	Record *EmbedRecordRef `json:"record,omitempty"`
+
+	// Populated for app.bsky.embed.recordWithMedia
+	Media *PostEmbed `json:"media,omitempty"`
+
+	// Populated for app.bsky.embed.video
+	Video *Blob  `json:"video,omitempty"`
+	Alt   string `json:"alt,omitempty"`
+}
+
+// EmbedImage is a single image blob attached to a post record.
+type EmbedImage struct {
+	Image Blob   `json:"image"`
+	Alt   string `json:"alt"`
+}
+
+// EmbedExternalData is the link card stored for an external URL.
+type EmbedExternalData struct {
+	URI         string `json:"uri"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Thumb       *Blob  `json:"thumb,omitempty"`
+}
+
+// EmbedRecordRef is a strong reference to a quoted post. For
+// app.bsky.embed.recordWithMedia the reference is nested within Record.
+type EmbedRecordRef struct {
+	URI    string          `json:"uri,omitempty"`
+	CID    string          `json:"cid,omitempty"`
+	Record *EmbedRecordRef `json:"record,omitempty"`
+}
+
+// Blob is a reference to binary content uploaded to the author's PDS.
+type Blob struct {
+	Type string `json:"$type"`
+	Ref  struct {
+		Link string `json:"$link"`
+	} `json:"ref"`
+	MimeType string `json:"mimeType"`
+	Size     int    `json:"size"`
+}
+
+// Facet annotates a byte range of a post's text with rich text features such as
+// links, mentions and hashtags.
+type Facet struct {
+	Index    FacetIndex     `json:"index"`
+	Features []FacetFeature `json:"features"`
+}
+
+// FacetIndex is the UTF-8 byte range of the post text that a `Facet` applies to.
+type FacetIndex struct {
+	ByteStart int `json:"byteStart"`
+	ByteEnd   int `json:"byteEnd"`
+}
+
+// FacetFeature describes a single rich text feature. Only the field relevant to
+// the reported Type is populated.
+type FacetFeature struct {
+	Type string `json:"$type"`
+	URI  string `json:"uri,omitempty"`
+	DID  string `json:"did,omitempty"`
+	Tag  string `json:"tag,omitempty"`
+}
+
+// URL returns the link that the feature resolves to, if any.
+func (f FacetFeature) URL() string {
+	switch f.Type {
+	case FacetLink:
+		return f.URI
+	case FacetMention:
+		return "https://bsky.app/profile/" + f.DID
+	case FacetTag:
+		return "https://bsky.app/hashtag/" + f.Tag
+	}
+
+	return ""
+}
@@ -0,0 +1,123 @@
+package bluesky
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/gorilla/websocket"
+)
+
+// Collections of the records that are relayed from the Jetstream.
+const (
+	CollectionPost   = "app.bsky.feed.post"
+	CollectionRepost = "app.bsky.feed.repost"
+)
+
+// Jetstream subscribes to a Jetstream instance, which streams the commits of the
+// network as simplified JSON events. See https://github.com/bluesky-social/jetstream.
+type Jetstream struct {
+	URL    string
+	Dialer *websocket.Dialer
+}
+
+// NewJetstream creates a new client for the Jetstream at the given URL, such as
+// "wss://jetstream2.us-east.bsky.network/subscribe".
+func NewJetstream(url string) *Jetstream {
+	return &Jetstream{
+		URL:    url,
+		Dialer: websocket.DefaultDialer,
+	}
+}
+
+// JetstreamOptions filters the events received from the Jetstream.
+type JetstreamOptions struct {
+	// Collections limits commit events to the given record collections.
+	Collections []string
+
+	// DIDs limits events to the given repositories. Leaving this empty subscribes
+	// to the entire network.
+	DIDs []string
+
+	// Cursor is the `JetstreamEvent.TimeUS` to replay the stream from. Leave as
+	// 0 to only receive live events.
+	Cursor int64
+}
+
+// JetstreamEvent is a single event received from the Jetstream.
+type JetstreamEvent struct {
+	DID    string           `json:"did"`
+	TimeUS int64            `json:"time_us"`
+	Kind   string           `json:"kind"`
+	Commit *JetstreamCommit `json:"commit,omitempty"`
+}
+
+// JetstreamCommit describes a record that was changed within a repository.
+type JetstreamCommit struct {
+	Rev        string          `json:"rev"`
+	Operation  string          `json:"operation"`
+	Collection string          `json:"collection"`
+	RKey       string          `json:"rkey"`
+	Record     json.RawMessage `json:"record,omitempty"`
+	CID        string          `json:"cid"`
+}
+
+// Supported values for `JetstreamEvent.Kind` and `JetstreamCommit.Operation`.
+const (
+	JetstreamKindCommit = "commit"
+
+	JetstreamOperationCreate = "create"
+	JetstreamOperationUpdate = "update"
+	JetstreamOperationDelete = "delete"
+)
+
+// URI returns the AT URI of the record that was committed.
+func (e *JetstreamEvent) URI() string {
+	if e.Commit == nil {
+		return ""
+	}
+	return fmt.Sprintf("at://%s/%s/%s", e.DID, e.Commit.Collection, e.Commit.RKey)
+}
+
+// JetstreamConn is an open subscription to the Jetstream.
+type JetstreamConn struct {
+	conn *websocket.Conn
+}
+
+// Connect opens a subscription to the Jetstream with the given filters.
+func (j *Jetstream) Connect(ctx context.Context, opts JetstreamOptions) (*JetstreamConn, error) {
+	params := url.Values{}
+	for _, collection := range opts.Collections {
+		params.Add("wantedCollections", collection)
+	}
+	for _, did := range opts.DIDs {
+		params.Add("wantedDids", did)
+	}
+	if opts.Cursor > 0 {
+		params.Add("cursor", strconv.FormatInt(opts.Cursor, 10))
+	}
+
+	conn, resp, err := j.Dialer.DialContext(ctx, j.URL+"?"+params.Encode(), nil)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("jetstream error %d: %w", resp.StatusCode, err)
+		}
+		return nil, err
+	}
+
+	return &JetstreamConn{conn: conn}, nil
+}
+
+// Next blocks until the next event is received from the Jetstream.
+func (c *JetstreamConn) Next() (JetstreamEvent, error) {
+	var ret JetstreamEvent
+	err := c.conn.ReadJSON(&ret)
+	return ret, err
+}
+
+// Close ends the subscription, causing any pending `Next` call to return.
+func (c *JetstreamConn) Close() error {
+	return c.conn.Close()
+}
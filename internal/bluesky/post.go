@@ -6,12 +6,26 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
 
 type FeedData struct {
-	Feed []FeedPostEntry `json:"feed"`
+	Feed   []FeedPostEntry `json:"feed"`
+	Cursor string          `json:"cursor,omitempty"`
+}
+
+// FeedOptions controls which page of an author's feed is returned by `GetUserFeed`.
+type FeedOptions struct {
+	// Cursor is the value returned in `FeedData.Cursor` by the previous page.
+	// Leave empty to fetch the most recent page.
+	Cursor string
+
+	// Limit is the maximum number of entries to return, between 1 and 100.
+	// Leave as 0 to use the API default.
+	Limit int
 }
 
 type FeedPostEntry struct {
@@ -19,6 +33,16 @@ type FeedPostEntry struct {
 	Reason *FeedPostReason `json:"reason,omitempty"`
 }
 
+// SortAt returns the time the entry appeared in the author's feed. For reposts
+// this is the time of the repost rather than of the original post.
+func (e *FeedPostEntry) SortAt() time.Time {
+	if e.Reason != nil && !e.Reason.IndexedAt.IsZero() {
+		return e.Reason.IndexedAt
+	}
+
+	return e.Post.IndexedAt
+}
+
 type FeedPostReason struct {
 	Type      string    `json:"$type"`
 	By        *ByActor  `json:"by,omitempty"`
@@ -36,7 +60,7 @@ type ByActor struct {
 type FeedPost struct {
 	Author      FeedPostAuthor `json:"author"`
 	CID         string         `json:"cid"`
-	Embed       any            `json:"embed"`
+	Embed       *PostEmbedView `json:"embed,omitempty"`
 	IndexedAt   time.Time      `json:"indexedAt"`
 	Labels      []any          `json:"labels"`
 	LikeCount   int            `json:"likeCount"`
@@ -59,17 +83,73 @@ type FeedPostAuthor struct {
 
 // FeedPostRecord contains the bulk of the information about the contents of a given `FeedPost`.
 type FeedPostRecord struct {
-	Type      string    `json:"$type"`
-	CreatedAt time.Time `json:"createdAt"`
-	Embed     any       `json:"embed"`
-	Facets    any       `json:"facets"`
-	Text      string    `json:"text"`
+	Type      string     `json:"$type"`
+	CreatedAt time.Time  `json:"createdAt"`
+	Embed     *PostEmbed `json:"embed,omitempty"`
+	Facets    []Facet    `json:"facets,omitempty"`
+	Reply     *PostReply `json:"reply,omitempty"`
+	Text      string     `json:"text"`
+}
+
+// PostReply identifies the thread that a reply `FeedPostRecord` belongs to.
+type PostReply struct {
+	Root   StrongRef `json:"root"`
+	Parent StrongRef `json:"parent"`
 }
 
-// GetUserFeed fetches user feed data by handle
-func (c *BlueskyClient) GetUserFeed(handle string) (*FeedData, error) {
+// StrongRef is a reference to a specific version of a record.
+type StrongRef struct {
+	URI string `json:"uri"`
+	CID string `json:"cid"`
+}
+
+// MaxGetPosts is the maximum number of URIs that may be passed to `GetPosts`.
+const MaxGetPosts = 25
+
+// GetPosts fetches the given posts by URI. Posts that have been deleted are
+// omitted from the result.
+func (c *BlueskyClient) GetPosts(uris []string) ([]FeedPost, error) {
+	if len(uris) > MaxGetPosts {
+		return nil, fmt.Errorf("cannot fetch more than %d posts at once", MaxGetPosts)
+	}
+
+	params := url.Values{}
+	for _, uri := range uris {
+		params.Add("uris", uri)
+	}
+	url := fmt.Sprintf("%s/xrpc/app.bsky.feed.getPosts?%s", c.BaseURL, params.Encode())
+
+	resp, err := c.HttpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+	}
+
+	var ret struct {
+		Posts []FeedPost `json:"posts"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+
+	return ret.Posts, nil
+}
+
+// GetUserFeed fetches a page of user feed data by handle
+func (c *BlueskyClient) GetUserFeed(handle string, opts FeedOptions) (*FeedData, error) {
 	params := url.Values{}
 	params.Add("actor", handle)
+	if opts.Cursor != "" {
+		params.Add("cursor", opts.Cursor)
+	}
+	if opts.Limit > 0 {
+		params.Add("limit", strconv.Itoa(opts.Limit))
+	}
 	url := fmt.Sprintf("%s/xrpc/app.bsky.feed.getAuthorFeed?%s", c.BaseURL, params.Encode())
 
 	resp, err := c.HttpClient.Get(url)
@@ -80,7 +160,7 @@ func (c *BlueskyClient) GetUserFeed(handle string) (*FeedData, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error: %s", string(bodyBytes))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
 	}
 
 	var ret FeedData
@@ -98,3 +178,61 @@ func (p *FeedPost) URL() string {
 	// Example: https://bsky.app/profile/destinythegame.bungie.net/post/3lnape7mfxs27
 	return fmt.Sprintf("https://bsky.app/profile/%s/post/%s", p.Author.Handle, postID)
 }
+
+// IsReply reports whether the post is a reply to another post.
+func (p *FeedPost) IsReply() bool {
+	return p.Record.Reply != nil
+}
+
+// IsQuote reports whether the post quotes another post.
+func (p *FeedPost) IsQuote() bool {
+	return p.Embed.Quoted() != nil
+}
+
+// HasMedia reports whether the post has images or a video attached.
+func (p *FeedPost) HasMedia() bool {
+	media := p.Embed.Attachment()
+	return media != nil && (media.Type == EmbedImagesView || media.Type == EmbedVideoView)
+}
+
+// URL returns the link to the author's Bluesky profile.
+func (a *FeedPostAuthor) URL() string {
+	return fmt.Sprintf("https://bsky.app/profile/%s", a.Handle)
+}
+
+// Markdown renders the record text with each of its link, mention and tag facets
+// resolved to a Markdown link.
+func (r *FeedPostRecord) Markdown() string {
+	facets := slices.Clone(r.Facets)
+	slices.SortFunc(facets, func(a, b Facet) int {
+		return a.Index.ByteStart - b.Index.ByteStart
+	})
+
+	ret := strings.Builder{}
+	last := 0
+	for _, facet := range facets {
+		start, end := facet.Index.ByteStart, facet.Index.ByteEnd
+
+		// Skip any facets that overlap a previous one or fall outside of the text
+		if start < last || end <= start || end > len(r.Text) {
+			continue
+		}
+
+		link := ""
+		for _, feature := range facet.Features {
+			if link = feature.URL(); link != "" {
+				break
+			}
+		}
+		if link == "" {
+			continue
+		}
+
+		ret.WriteString(r.Text[last:start])
+		fmt.Fprintf(&ret, "[%s](%s)", r.Text[start:end], link)
+		last = end
+	}
+	ret.WriteString(r.Text[last:])
+
+	return ret.String()
+}
@@ -0,0 +1,116 @@
+package bluesky
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFeedPostRecord_Markdown(t *testing.T) {
+	tests := []struct {
+		name   string
+		record FeedPostRecord
+		want   string
+	}{
+		{
+			name:   "no facets",
+			record: FeedPostRecord{Text: "Hello, world!"},
+			want:   "Hello, world!",
+		},
+		{
+			name: "link",
+			record: FeedPostRecord{
+				Text: "Read the notes at bungie.net/7020 today",
+				Facets: []Facet{
+					{
+						Index:    FacetIndex{ByteStart: 18, ByteEnd: 33},
+						Features: []FacetFeature{{Type: FacetLink, URI: "https://bungie.net/7020"}},
+					},
+				},
+			},
+			want: "Read the notes at [bungie.net/7020](https://bungie.net/7020) today",
+		},
+		{
+			name: "unordered mention and tag",
+			record: FeedPostRecord{
+				Text: "Ask @bungiehelp.bungie.net about #Destiny2",
+				Facets: []Facet{
+					{
+						Index:    FacetIndex{ByteStart: 33, ByteEnd: 42},
+						Features: []FacetFeature{{Type: FacetTag, Tag: "Destiny2"}},
+					},
+					{
+						Index:    FacetIndex{ByteStart: 4, ByteEnd: 26},
+						Features: []FacetFeature{{Type: FacetMention, DID: "did:plc:abc"}},
+					},
+				},
+			},
+			want: "Ask [@bungiehelp.bungie.net](https://bsky.app/profile/did:plc:abc) about [#Destiny2](https://bsky.app/hashtag/Destiny2)",
+		},
+		{
+			name: "multi-byte text",
+			record: FeedPostRecord{
+				Text: "🔧 Maintenance: status.bungie.net",
+				Facets: []Facet{
+					{
+						Index:    FacetIndex{ByteStart: 18, ByteEnd: 35},
+						Features: []FacetFeature{{Type: FacetLink, URI: "https://status.bungie.net"}},
+					},
+				},
+			},
+			want: "🔧 Maintenance: [status.bungie.net](https://status.bungie.net)",
+		},
+		{
+			name: "out of range facet",
+			record: FeedPostRecord{
+				Text: "short",
+				Facets: []Facet{
+					{
+						Index:    FacetIndex{ByteStart: 2, ByteEnd: 50},
+						Features: []FacetFeature{{Type: FacetLink, URI: "https://example.com"}},
+					},
+				},
+			},
+			want: "short",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.record.Markdown(); got != tt.want {
+				t.Errorf("Markdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostEmbedView_Unmarshal(t *testing.T) {
+	raw := `{
+		"$type": "app.bsky.embed.recordWithMedia#view",
+		"record": {
+			"record": {
+				"$type": "app.bsky.embed.record#viewRecord",
+				"uri": "at://did:plc:abc/app.bsky.feed.post/123",
+				"author": {"handle": "destinythegame.bungie.net"},
+				"value": {"text": "Quoted post"}
+			}
+		},
+		"media": {
+			"$type": "app.bsky.embed.images#view",
+			"images": [{"thumb": "https://cdn/thumb.jpg", "fullsize": "https://cdn/full.jpg", "alt": "A Guardian"}]
+		}
+	}`
+
+	var embed PostEmbedView
+	if err := json.Unmarshal([]byte(raw), &embed); err != nil {
+		t.Fatal(err)
+	}
+
+	quoted := embed.Quoted()
+	if quoted == nil || quoted.Value == nil || quoted.Value.Text != "Quoted post" {
+		t.Errorf("Quoted() = %+v, want the quoted post", quoted)
+	}
+
+	media := embed.Attachment()
+	if media == nil || len(media.Images) != 1 || media.Images[0].Fullsize != "https://cdn/full.jpg" {
+		t.Errorf("Attachment() = %+v, want the image embed", media)
+	}
+}
@@ -14,6 +14,8 @@ func InitCache(newCache cache.Cache) {
 
 // state represents the internal persistence layer between each user's invocation.
 type state struct {
+	// TZ is the IANA name of the user's timezone, such as "America/New_York".
+	// Older states may hold an abbreviation, which parseTimezone also accepts.
 	TZ string `json:"tz"`
 }
 
@@ -28,5 +30,11 @@ func generateStateKey(i *discordgo.InteractionCreate) string {
 		userID = i.Member.User.ID
 	}
 
+	return userStateKey(userID)
+}
+
+// userStateKey returns the key of the given user's state, for users other than
+// the one interacting, such as the author of a message.
+func userStateKey(userID string) string {
 	return dbUserPrefix + userID
 }
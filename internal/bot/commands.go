@@ -10,6 +10,7 @@ import (
 	"github.com/bwmarrin/discordgo"
 	"github.com/taiidani/go-lib/cache"
 	"github.com/taiidani/no-time-to-explain/internal/db/models"
+	"github.com/taiidani/no-time-to-explain/internal/triggers"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -37,9 +38,10 @@ type Commands struct {
 	s        *discordgo.Session
 	db       cache.Cache
 	queries  *models.Queries
+	triggers *triggers.Index
 }
 
-func NewCommands(session *discordgo.Session, conn *sql.DB, db cache.Cache) *Commands {
+func NewCommands(session *discordgo.Session, conn *sql.DB, db cache.Cache, index *triggers.Index) *Commands {
 	ret := Commands{
 		commands: []applicationCommand{
 			{
@@ -47,15 +49,65 @@ func NewCommands(session *discordgo.Session, conn *sql.DB, db cache.Cache) *Comm
 					Name:        "time",
 					Description: "Render a Discord-style timestamp for sharing with others",
 					Type:        discordgo.ChatApplicationCommand,
-					Options:     []*discordgo.ApplicationCommandOption{},
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "when",
+							Description: `When to render, such as "tomorrow 8pm", "friday 19:30 CET" or "in 2 hours"`,
+						},
+						{
+							Type:         discordgo.ApplicationCommandOptionString,
+							Name:         "tz",
+							Description:  "Timezone to render in, instead of your saved timezone",
+							Autocomplete: true,
+						},
+					},
 				},
-				Handler: timeHandler,
+				Autocomplete: timezoneAutocomplete,
+				Handler:      timeHandler,
 				MessageComponents: map[string]func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate){
 					changeTimeCustomID:      changeTimeHandler,
 					nowTimeCustomID:         nowTimeHandler,
 					changeTimeModalCustomID: changeTimeSubmitHandler,
+					shareTimeCustomID:       shareTimeHandler,
+					shareTimeModalCustomID:  shareTimeSubmitHandler,
 				},
 			},
+			{
+				Command: &discordgo.ApplicationCommand{
+					Name:        "timezone",
+					Description: "Manage the timezone that your timestamps are rendered in",
+					Type:        discordgo.ChatApplicationCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionSubCommand,
+							Name:        "set",
+							Description: "Save your timezone",
+							Options: []*discordgo.ApplicationCommandOption{
+								{
+									Type:         discordgo.ApplicationCommandOptionString,
+									Name:         "zone",
+									Description:  `Your timezone, such as "America/New_York", "London" or "CET"`,
+									Required:     true,
+									Autocomplete: true,
+								},
+							},
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionSubCommand,
+							Name:        "show",
+							Description: "Show your saved timezone",
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionSubCommand,
+							Name:        "clear",
+							Description: "Clear your saved timezone",
+						},
+					},
+				},
+				Autocomplete: timezoneAutocomplete,
+				Handler:      timezoneHandler,
+			},
 			{
 				Command: &discordgo.ApplicationCommand{
 					// Parse Charlemagne events and generate exportable calendar items
@@ -65,11 +117,21 @@ func NewCommands(session *discordgo.Session, conn *sql.DB, db cache.Cache) *Comm
 				},
 				Handler: eventCalendarHandler,
 			},
+			{
+				Command: &discordgo.ApplicationCommand{
+					// Convert the times written in a message into timestamps
+					Name:    "Convert Times",
+					Type:    discordgo.MessageApplicationCommand,
+					Options: []*discordgo.ApplicationCommandOption{},
+				},
+				Handler: convertTimesHandler,
+			},
 		},
 		registry: []*discordgo.ApplicationCommand{},
 		s:        session,
 		db:       db,
 		queries:  models.New(conn),
+		triggers: index,
 	}
 
 	return &ret
@@ -115,14 +177,12 @@ func (c *Commands) handleCommand(s *discordgo.Session, i *discordgo.InteractionC
 			if cmd.Autocomplete != nil && cmd.Command.Name == i.ApplicationCommandData().Name {
 				span.SetName(cmd.Command.Name + "-autocomplete")
 
-				for _, opt := range i.ApplicationCommandData().Options {
-					if opt.Focused {
-						choices := cmd.Autocomplete(ctx, s, i, opt)
-						_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-							Type: discordgo.InteractionApplicationCommandAutocompleteResult,
-							Data: &discordgo.InteractionResponseData{Choices: choices},
-						})
-					}
+				if opt := focusedOption(i.ApplicationCommandData().Options); opt != nil {
+					choices := cmd.Autocomplete(ctx, s, i, opt)
+					_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+						Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+						Data: &discordgo.InteractionResponseData{Choices: choices},
+					})
 				}
 			}
 		case discordgo.InteractionMessageComponent:
@@ -155,6 +215,20 @@ func (c *Commands) handleCommand(s *discordgo.Session, i *discordgo.InteractionC
 	}
 }
 
+// focusedOption returns the option that the user is typing into, searching
+// the options of any subcommands.
+func focusedOption(options []*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+	for _, opt := range options {
+		if opt.Focused {
+			return opt
+		}
+		if ret := focusedOption(opt.Options); ret != nil {
+			return ret
+		}
+	}
+	return nil
+}
+
 func (c *Commands) Teardown() {
 	for _, cmd := range c.registry {
 		log := slog.With("name", cmd.Name, "application-id", cmd.ApplicationID, "id", cmd.ID)
@@ -0,0 +1,179 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxConvertedTimes limits the times converted from a single message, keeping
+// the reply within Discord's limits on embeds.
+const maxConvertedTimes = 5
+
+var (
+	// timestampTagPattern matches Discord timestamp tags such as "<t:1716073200:F>".
+	timestampTagPattern = regexp.MustCompile(`<t:(-?\d+)(?::[tTdDfFR])?>`)
+
+	// writtenTimePattern matches times written in text such as "8pm EST",
+	// "20:00 CET" or "friday at 7:30 pm", capturing any day before the time and
+	// the word after it, which may be a timezone.
+	writtenTimePattern = regexp.MustCompile(`(?i)(?:\b(today|tonight|tomorrow|(?:mon|tues?|wed(?:nes)?|thu(?:rs?)?|fri|sat(?:ur)?|sun)(?:day)?)\s+(?:at\s+)?)?\b(\d{1,2}:\d{2}(?:\s*[ap]m)?|\d{1,2}\s*[ap]m)\b(?:\s+([a-z][\w/+:-]*))?`)
+)
+
+// foundTime is a time found in the text of a message.
+type foundTime struct {
+	Text  string
+	Time  time.Time
+	index int
+}
+
+func convertTimesHandler(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	ctx, span := tracer.Start(ctx, "convertTimesHandler")
+	defer span.End()
+
+	data := i.ApplicationCommandData()
+	var msg *discordgo.Message
+	if data.Resolved != nil {
+		msg = data.Resolved.Messages[data.TargetID]
+	}
+	if msg == nil {
+		errorMessage(s, i.Interaction, fmt.Errorf("message not found"))
+		return
+	}
+
+	// Times written without a timezone are read in the author's timezone
+	var author *time.Location
+	if msg.Author != nil {
+		if tz, saved := loadTimezone(ctx, userStateKey(msg.Author.ID)); saved {
+			author = tz
+		}
+	}
+
+	found := findTimes(messageText(msg), msg.Timestamp, author)
+	if len(found) == 0 {
+		errorMessage(s, i.Interaction, fmt.Errorf("no times were found in the message. Times written without a timezone are only converted once their author has saved one with /timezone set"))
+		return
+	}
+
+	tz, saved := loadTimezone(ctx, generateStateKey(i))
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: convertTimesResponse(found, tz, saved),
+	})
+	if err != nil {
+		slog.Warn("Could not respond to user message", "err", err)
+		commandError(s, i.Interaction, err)
+		return
+	}
+}
+
+// messageText returns the text of the message along with that of its embeds,
+// where bots often write their times.
+func messageText(msg *discordgo.Message) string {
+	text := []string{msg.Content}
+	for _, embed := range msg.Embeds {
+		text = append(text, embed.Title, embed.Description)
+		for _, field := range embed.Fields {
+			text = append(text, field.Name, field.Value)
+		}
+	}
+	return strings.Join(text, "\n")
+}
+
+// findTimes finds the timestamp tags and written times in the text, in the
+// order that they appear. Written times are resolved as their next occurrence
+// after the message was sent. Those without a timezone are read in the
+// author's timezone, and skipped if the author has not saved one.
+func findTimes(text string, sent time.Time, author *time.Location) []foundTime {
+	ret := []foundTime{}
+	seen := map[int64]bool{}
+	add := func(found foundTime) {
+		if !seen[found.Time.Unix()] {
+			seen[found.Time.Unix()] = true
+			ret = append(ret, found)
+		}
+	}
+
+	for _, match := range timestampTagPattern.FindAllStringSubmatchIndex(text, -1) {
+		unix, err := strconv.ParseInt(text[match[2]:match[3]], 10, 64)
+		if err != nil {
+			continue
+		}
+		add(foundTime{Text: text[match[0]:match[1]], Time: time.Unix(unix, 0), index: match[0]})
+	}
+
+	for _, match := range writtenTimePattern.FindAllStringSubmatchIndex(text, -1) {
+		group := func(n int) string {
+			if match[2*n] < 0 {
+				return ""
+			}
+			return text[match[2*n]:match[2*n+1]]
+		}
+		day, clock, word := group(1), group(2), group(3)
+		end := match[5]
+
+		// The word after the time is only part of it when it is a timezone
+		loc := author
+		if word != "" {
+			if tz, err := parseTimezoneNear(word, author); err == nil {
+				loc = tz
+				end = match[7]
+			}
+		}
+		if loc == nil {
+			continue
+		}
+
+		start := match[4]
+		if day != "" {
+			start = match[2]
+		}
+
+		tm, err := parseWhen(strings.Join([]string{day, clock}, " "), sent.In(loc))
+		if err != nil {
+			continue
+		}
+		add(foundTime{Text: text[start:end], Time: tm, index: start})
+	}
+
+	sort.SliceStable(ret, func(a, b int) bool { return ret[a].index < ret[b].index })
+	return ret
+}
+
+// convertTimesResponse renders each time found as Discord's timestamp tags, and
+// in the timezone of the user who asked for them.
+func convertTimesResponse(found []foundTime, tz *time.Location, saved bool) *discordgo.InteractionResponseData {
+	ret := &discordgo.InteractionResponseData{
+		Flags: discordgo.MessageFlagsEphemeral,
+	}
+
+	notes := []string{}
+	if len(found) > maxConvertedTimes {
+		notes = append(notes, fmt.Sprintf("Showing the first %d of the %d times found.", maxConvertedTimes, len(found)))
+		found = found[:maxConvertedTimes]
+	}
+	if !saved {
+		notes = append(notes, "Save your timezone with `/timezone set` to see these times in it.")
+	}
+	ret.Content = strings.Join(notes, "\n")
+
+	for _, f := range found {
+		ret.Embeds = append(ret.Embeds, &discordgo.MessageEmbed{
+			Title: f.Text,
+			Color: defaultColor,
+			Description: fmt.Sprintf("<t:%d:F> (<t:%d:R>)\nIn %s that is %s",
+				f.Time.Unix(), f.Time.Unix(), tz, f.Time.In(tz).Format("Monday, January 2 at 3:04 PM MST")),
+			Fields: timestampFields(f.Time),
+			Footer: &discordgo.MessageEmbedFooter{Text: defaultFooter},
+		})
+	}
+
+	return ret
+}
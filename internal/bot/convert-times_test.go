@@ -0,0 +1,133 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func Test_findTimes(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A Wednesday afternoon in New York
+	sent := time.Date(2025, time.October, 15, 19, 0, 0, 0, time.UTC)
+
+	type found struct {
+		text string
+		time time.Time
+	}
+	tests := []struct {
+		name   string
+		text   string
+		author *time.Location
+		want   []found
+	}{
+		{
+			name: "abbreviation",
+			text: "Raid at 8pm EST, be there!",
+			want: []found{{"8pm EST", time.Date(2025, time.October, 15, 20, 0, 0, 0, newYork)}},
+		},
+		{
+			name: "24-hour with timezone",
+			text: "Starting 20:00 CET sharp",
+			want: []found{{"20:00 CET", time.Date(2025, time.October, 16, 20, 0, 0, 0, berlin)}},
+		},
+		{
+			name: "day and spaced meridiem",
+			text: "Friday at 7:30 pm Eastern works",
+			want: []found{{"Friday at 7:30 pm Eastern", time.Date(2025, time.October, 17, 19, 30, 0, 0, newYork)}},
+		},
+		{
+			name: "timestamp tags",
+			text: "Starts <t:1716073200:F> (<t:1716073200:R>) and ends <t:1716080400>",
+			want: []found{
+				{"<t:1716073200:F>", time.Unix(1716073200, 0)},
+				{"<t:1716080400>", time.Unix(1716080400, 0)},
+			},
+		},
+		{
+			name:   "author timezone",
+			text:   "See you at 9pm tonight",
+			author: newYork,
+			want:   []found{{"9pm", time.Date(2025, time.October, 15, 21, 0, 0, 0, newYork)}},
+		},
+		{
+			name: "no timezone",
+			text: "See you at 9pm tonight",
+		},
+		{
+			name: "in order",
+			text: "Either 9pm UTC or <t:1760554800:t>",
+			want: []found{
+				{"9pm UTC", time.Date(2025, time.October, 15, 21, 0, 0, 0, time.UTC)},
+				{"<t:1760554800:t>", time.Unix(1760554800, 0)},
+			},
+		},
+		{
+			name: "no times",
+			text: "Nothing to see here at 8 o'clock",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findTimes(tt.text, sent, tt.author)
+			if len(got) != len(tt.want) {
+				t.Fatalf("findTimes() = %v, want %v", got, tt.want)
+			}
+			for n, want := range tt.want {
+				if got[n].Text != want.text || !got[n].Time.Equal(want.time) {
+					t.Errorf("findTimes()[%d] = %q %v, want %q %v", n, got[n].Text, got[n].Time, want.text, want.time)
+				}
+			}
+		})
+	}
+}
+
+func Test_messageText(t *testing.T) {
+	msg := &discordgo.Message{
+		Content: "content",
+		Embeds: []*discordgo.MessageEmbed{{
+			Title:       "title",
+			Description: "description",
+			Fields:      []*discordgo.MessageEmbedField{{Name: "name", Value: "value"}},
+		}},
+	}
+
+	if got, want := messageText(msg), "content\ntitle\ndescription\nname\nvalue"; got != want {
+		t.Errorf("messageText() = %q, want %q", got, want)
+	}
+}
+
+func Test_convertTimesResponse(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := []foundTime{}
+	for n := range maxConvertedTimes + 1 {
+		found = append(found, foundTime{Text: "8pm CET", Time: time.Date(2025, time.October, 15+n, 18, 0, 0, 0, time.UTC)})
+	}
+
+	got := convertTimesResponse(found, newYork, true)
+	if got.Flags != discordgo.MessageFlagsEphemeral {
+		t.Errorf("convertTimesResponse() is not ephemeral")
+	}
+	if len(got.Embeds) != maxConvertedTimes {
+		t.Fatalf("convertTimesResponse() has %d embeds, want %d", len(got.Embeds), maxConvertedTimes)
+	}
+	if want := "<t:1760551200:F> (<t:1760551200:R>)\nIn America/New_York that is Wednesday, October 15 at 2:00 PM EDT"; got.Embeds[0].Description != want {
+		t.Errorf("convertTimesResponse() description = %q, want %q", got.Embeds[0].Description, want)
+	}
+	if got.Content != "Showing the first 5 of the 6 times found." {
+		t.Errorf("convertTimesResponse() content = %q", got.Content)
+	}
+}
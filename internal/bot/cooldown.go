@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/taiidani/no-time-to-explain/internal/triggers"
+)
+
+// Reasons that a response was suppressed, recorded on the message span.
+const (
+	cooldownChannel = "channel_cooldown"
+	cooldownUser    = "user_cooldown"
+	cooldownRate    = "rate_limit"
+)
+
+// rateLimitWindow is the period that a message's rate limit is counted over.
+const rateLimitWindow = time.Minute
+
+// cooldownLock serializes checking and starting the cooldowns of responses, as
+// the cache has no atomic operations. Cooldowns shared in Redis by several bot
+// instances can still be passed by messages that arrive together, as the lock
+// only covers this process.
+var cooldownLock sync.Mutex
+
+// tryStartCooldown reports whether the selected response must be suppressed,
+// and why. Otherwise it starts the response's cooldowns and counts it towards
+// its rate limit before the response is sent, so that messages arriving
+// together cannot all pass them. The returned error is for failing to record
+// the cooldowns, which never suppresses the response.
+func tryStartCooldown(ctx context.Context, trigger triggers.Trigger, msg messageContext, now time.Time) (string, bool, error) {
+	cooldownLock.Lock()
+	defer cooldownLock.Unlock()
+
+	if reason, suppressed := onCooldown(ctx, trigger, msg, now); suppressed {
+		return reason, true, nil
+	}
+	return "", false, startCooldown(ctx, trigger, msg, now)
+}
+
+// releaseCooldown gives back the cooldowns started by tryStartCooldown for a
+// response that could not be sent. The cache cannot delete keys, so released
+// cooldowns are blanked instead.
+func releaseCooldown(ctx context.Context, trigger triggers.Trigger, msg messageContext, now time.Time) error {
+	cooldownLock.Lock()
+	defer cooldownLock.Unlock()
+
+	if trigger.ChannelCooldown > 0 {
+		if err := cacheClient.Set(ctx, channelCooldownKey(trigger, msg), time.Time{}, trigger.ChannelCooldownDuration()); err != nil {
+			return err
+		}
+	}
+
+	if trigger.UserCooldown > 0 && msg.Sender != nil {
+		if err := cacheClient.Set(ctx, userCooldownKey(trigger, msg), time.Time{}, trigger.UserCooldownDuration()); err != nil {
+			return err
+		}
+	}
+
+	if trigger.RateLimit > 0 {
+		key := rateLimitKey(trigger, now)
+
+		var count int32
+		if err := cacheClient.Get(ctx, key, &count); err == nil && count > 0 {
+			return cacheClient.Set(ctx, key, count-1, rateLimitWindow)
+		}
+	}
+
+	return nil
+}
+
+// onCooldown reports whether the selected response must be suppressed, and
+// why. The cooldowns are kept in the cache so that they survive restarts when
+// it is backed by Redis. Cache errors never suppress a response.
+func onCooldown(ctx context.Context, trigger triggers.Trigger, msg messageContext, now time.Time) (string, bool) {
+	var last time.Time
+
+	if trigger.ChannelCooldown > 0 {
+		if err := cacheClient.Get(ctx, channelCooldownKey(trigger, msg), &last); err == nil && !last.IsZero() {
+			return cooldownChannel, true
+		}
+	}
+
+	if trigger.UserCooldown > 0 && msg.Sender != nil {
+		if err := cacheClient.Get(ctx, userCooldownKey(trigger, msg), &last); err == nil && !last.IsZero() {
+			return cooldownUser, true
+		}
+	}
+
+	if trigger.RateLimit > 0 {
+		var count int32
+		if err := cacheClient.Get(ctx, rateLimitKey(trigger, now), &count); err == nil && count >= trigger.RateLimit {
+			return cooldownRate, true
+		}
+	}
+
+	return "", false
+}
+
+// startCooldown starts the response's cooldowns and counts it towards its rate
+// limit. Callers hold cooldownLock.
+func startCooldown(ctx context.Context, trigger triggers.Trigger, msg messageContext, now time.Time) error {
+	if trigger.ChannelCooldown > 0 {
+		if err := cacheClient.Set(ctx, channelCooldownKey(trigger, msg), now, trigger.ChannelCooldownDuration()); err != nil {
+			return err
+		}
+	}
+
+	if trigger.UserCooldown > 0 && msg.Sender != nil {
+		if err := cacheClient.Set(ctx, userCooldownKey(trigger, msg), now, trigger.UserCooldownDuration()); err != nil {
+			return err
+		}
+	}
+
+	if trigger.RateLimit > 0 {
+		key := rateLimitKey(trigger, now)
+
+		var count int32
+		_ = cacheClient.Get(ctx, key, &count)
+		if err := cacheClient.Set(ctx, key, count+1, rateLimitWindow); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func channelCooldownKey(trigger triggers.Trigger, msg messageContext) string {
+	return fmt.Sprintf("cooldown:message:%d:channel:%s", trigger.ID, msg.ChannelID)
+}
+
+func userCooldownKey(trigger triggers.Trigger, msg messageContext) string {
+	return fmt.Sprintf("cooldown:message:%d:user:%s", trigger.ID, msg.Sender.ID)
+}
+
+// rateLimitKey buckets the responses of a message by the minute they were sent.
+func rateLimitKey(trigger triggers.Trigger, now time.Time) string {
+	return fmt.Sprintf("ratelimit:message:%d:%d", trigger.ID, now.Truncate(rateLimitWindow).Unix())
+}
@@ -0,0 +1,190 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/taiidani/no-time-to-explain/internal/db/models"
+	"github.com/taiidani/no-time-to-explain/internal/triggers"
+)
+
+// memoryCache is a cache.Cache that stores values as JSON, as Redis does.
+type memoryCache struct {
+	mu      sync.Mutex
+	values  map[string][]byte
+	expires map[string]time.Time
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{values: map[string][]byte{}, expires: map[string]time.Time{}}
+}
+
+func (m *memoryCache) Get(_ context.Context, key string, val any) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, found := m.values[key]
+	if !found || time.Now().After(m.expires[key]) {
+		return errors.New("not found")
+	}
+	return json.Unmarshal(data, val)
+}
+
+func (m *memoryCache) Set(_ context.Context, key string, val any, ttl time.Duration) error {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = data
+	m.expires[key] = time.Now().Add(ttl)
+	return nil
+}
+
+func (m *memoryCache) Keys(_ context.Context, _ string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ret := []string{}
+	for key := range m.values {
+		ret = append(ret, key)
+	}
+	return ret, nil
+}
+
+func Test_onCooldown(t *testing.T) {
+	alice := &discordgo.User{ID: "1"}
+	bob := &discordgo.User{ID: "2"}
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		message models.Message
+		sent    []messageContext
+		msg     messageContext
+		want    string
+	}{
+		{
+			name:    "no cooldown",
+			message: models.Message{ID: 1},
+			sent:    []messageContext{{Sender: alice, ChannelID: "10"}},
+			msg:     messageContext{Sender: alice, ChannelID: "10"},
+		},
+		{
+			name:    "channel cooldown",
+			message: models.Message{ID: 1, ChannelCooldown: 60},
+			sent:    []messageContext{{Sender: alice, ChannelID: "10"}},
+			msg:     messageContext{Sender: bob, ChannelID: "10"},
+			want:    cooldownChannel,
+		},
+		{
+			name:    "channel cooldown in another channel",
+			message: models.Message{ID: 1, ChannelCooldown: 60},
+			sent:    []messageContext{{Sender: alice, ChannelID: "10"}},
+			msg:     messageContext{Sender: alice, ChannelID: "11"},
+		},
+		{
+			name:    "user cooldown",
+			message: models.Message{ID: 1, UserCooldown: 60},
+			sent:    []messageContext{{Sender: alice, ChannelID: "10"}},
+			msg:     messageContext{Sender: alice, ChannelID: "11"},
+			want:    cooldownUser,
+		},
+		{
+			name:    "user cooldown for another user",
+			message: models.Message{ID: 1, UserCooldown: 60},
+			sent:    []messageContext{{Sender: alice, ChannelID: "10"}},
+			msg:     messageContext{Sender: bob, ChannelID: "10"},
+		},
+		{
+			name:    "user cooldown without a sender",
+			message: models.Message{ID: 1, UserCooldown: 60},
+			sent:    []messageContext{{ChannelID: "10"}},
+			msg:     messageContext{ChannelID: "10"},
+		},
+		{
+			name:    "under rate limit",
+			message: models.Message{ID: 1, RateLimit: 2},
+			sent:    []messageContext{{Sender: alice, ChannelID: "10"}},
+			msg:     messageContext{Sender: bob, ChannelID: "11"},
+		},
+		{
+			name:    "rate limited",
+			message: models.Message{ID: 1, RateLimit: 2},
+			sent:    []messageContext{{Sender: alice, ChannelID: "10"}, {Sender: bob, ChannelID: "11"}},
+			msg:     messageContext{Sender: bob, ChannelID: "12"},
+			want:    cooldownRate,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := cacheClient
+			cacheClient = newMemoryCache()
+			t.Cleanup(func() { cacheClient = previous })
+
+			trigger := triggers.Trigger{Message: tt.message}
+			for _, sent := range tt.sent {
+				if err := startCooldown(t.Context(), trigger, sent, now); err != nil {
+					t.Fatalf("startCooldown() error = %v", err)
+				}
+			}
+
+			got, suppressed := onCooldown(t.Context(), trigger, tt.msg, now)
+			if got != tt.want || suppressed != (tt.want != "") {
+				t.Errorf("onCooldown() = %q, %v, want %q", got, suppressed, tt.want)
+			}
+		})
+	}
+}
+
+func Test_releaseCooldown(t *testing.T) {
+	previous := cacheClient
+	cacheClient = newMemoryCache()
+	t.Cleanup(func() { cacheClient = previous })
+
+	now := time.Now()
+	trigger := triggers.Trigger{Message: models.Message{ID: 1, ChannelCooldown: 60, UserCooldown: 60, RateLimit: 1}}
+	msg := messageContext{Sender: &discordgo.User{ID: "1"}, ChannelID: "10"}
+
+	if _, suppressed, err := tryStartCooldown(t.Context(), trigger, msg, now); suppressed || err != nil {
+		t.Fatalf("tryStartCooldown() = %v, %v, want the response sent", suppressed, err)
+	}
+	if err := releaseCooldown(t.Context(), trigger, msg, now); err != nil {
+		t.Fatalf("releaseCooldown() error = %v", err)
+	}
+	if reason, suppressed, err := tryStartCooldown(t.Context(), trigger, msg, now); suppressed || err != nil {
+		t.Errorf("tryStartCooldown() = %q, %v, %v after release, want the response sent", reason, suppressed, err)
+	}
+}
+
+func Test_tryStartCooldown_concurrent(t *testing.T) {
+	previous := cacheClient
+	cacheClient = newMemoryCache()
+	t.Cleanup(func() { cacheClient = previous })
+
+	now := time.Now()
+	trigger := triggers.Trigger{Message: models.Message{ID: 1, RateLimit: 1}}
+
+	var sent atomic.Int32
+	wg := sync.WaitGroup{}
+	for range 50 {
+		wg.Go(func() {
+			if _, suppressed, _ := tryStartCooldown(t.Context(), trigger, messageContext{ChannelID: "10"}, now); !suppressed {
+				sent.Add(1)
+			}
+		})
+	}
+	wg.Wait()
+
+	if got := sent.Load(); got != 1 {
+		t.Errorf("tryStartCooldown() let %d responses through, want 1", got)
+	}
+}
@@ -2,13 +2,16 @@ package bot
 
 import (
 	"context"
+	"database/sql"
 	"log/slog"
 	"math/rand"
-	"regexp"
+	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/taiidani/no-time-to-explain/internal/db/models"
+	"github.com/taiidani/no-time-to-explain/internal/response"
+	"github.com/taiidani/no-time-to-explain/internal/triggers"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 )
@@ -40,33 +43,239 @@ func (c *Commands) handleMessage(s *discordgo.Session, m *discordgo.MessageCreat
 	_ = cacheClient.Set(ctx, "recent-senders:"+m.Author.Username, m.Author, time.Hour*168)
 
 	// Determine the response based on the given content
-	messages, err := c.queries.LoadMessages(ctx)
+	compiled, err := c.triggers.Triggers(ctx)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 		log.ErrorContext(ctx, "Could not get messages from DB", "err", err)
 	}
 
-	ref := m.Message.Reference()
-	response := c.responseForTrigger(messages, m.Author, m.Content)
-	if response != "" {
-		log = log.With("response", response)
+	msg := messageContext{
+		Sender:    m.Author,
+		GuildID:   m.GuildID,
+		ChannelID: m.ChannelID,
+		Content:   m.Content,
+		Time:      m.Timestamp,
+	}
+	if m.Member != nil {
+		msg.RoleIDs = m.Member.Roles
+	}
+	selected, found := c.responseForTrigger(compiled, msg)
+	if found {
+		go c.recordHit(ctx, selected, msg)
 
-		var err error
-		if ref != nil {
-			log.InfoContext(ctx, "Sending message reply")
-			_, err = s.ChannelMessageSendReply(m.ChannelID, response, ref)
-		} else {
-			log.InfoContext(ctx, "Sending message")
-			_, err = s.ChannelMessageSend(m.ChannelID, response)
+		now := time.Now()
+		reason, suppressed, err := tryStartCooldown(ctx, selected, msg, now)
+		if err != nil {
+			log.WarnContext(ctx, "Could not record response cooldown", "err", err)
+		}
+		if suppressed {
+			span.SetAttributes(
+				attribute.Bool("suppressed", true),
+				attribute.String("suppressed_reason", reason),
+				attribute.Int("message_id", int(selected.ID)),
+			)
+			log.InfoContext(ctx, "Suppressing response on cooldown", "message-id", selected.ID, "reason", reason)
+			return
 		}
 
-		if err != nil {
+		response := selected.Template.Render(responseVars(s, m, selected))
+		log = log.With("response", response, "action", selected.Action)
+		span.SetAttributes(attribute.String("action", selected.Action))
+
+		if err := sendResponse(ctx, log, s, m, selected, response); err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, err.Error())
 			log.ErrorContext(ctx, "Could not send channel response", "err", err)
+
+			if err := releaseCooldown(ctx, selected, msg, now); err != nil {
+				log.WarnContext(ctx, "Could not release response cooldown", "err", err)
+			}
+			return
+		}
+	}
+}
+
+// recordHitTimeout bounds the writes of a single hit, so that a slow database
+// cannot pile up hits waiting to be recorded.
+const recordHitTimeout = 5 * time.Second
+
+// recordHit counts the selection of a response, including those suppressed by
+// a cooldown, so that unused triggers can be found from the admin page. It is
+// run alongside the response rather than delaying it.
+func (c *Commands) recordHit(ctx context.Context, trigger triggers.Trigger, msg messageContext) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordHitTimeout)
+	defer cancel()
+
+	now := time.Now().UTC()
+
+	userID := ""
+	if msg.Sender != nil {
+		userID = msg.Sender.ID
+	}
+
+	err := c.queries.RecordMessageHit(ctx, models.RecordMessageHitParams{
+		ID:            trigger.ID,
+		LastFiredAt:   sql.NullTime{Time: now, Valid: true},
+		LastChannelID: msg.ChannelID,
+		LastUserID:    userID,
+	})
+	if err == nil {
+		err = c.queries.RecordMessageHitDay(ctx, models.RecordMessageHitDayParams{
+			MessageID: trigger.ID,
+			Day:       now.Truncate(24 * time.Hour),
+		})
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "Could not record message hit", "message-id", trigger.ID, "err", err)
+	}
+}
+
+// sendResponse performs the action of the selected message in response to the
+// triggering chat message.
+func sendResponse(ctx context.Context, log *slog.Logger, s *discordgo.Session, m *discordgo.MessageCreate, trigger triggers.Trigger, response string) error {
+	ref := m.Message.Reference()
+
+	switch trigger.Action {
+	case models.ActionReaction:
+		log.InfoContext(ctx, "Adding reaction")
+		return s.MessageReactionAdd(m.ChannelID, m.ID, reactionEmoji(response), discordgo.WithContext(ctx))
+
+	case models.ActionEmbed:
+		log.InfoContext(ctx, "Sending embed")
+		embed := &discordgo.MessageEmbed{
+			Type:        discordgo.EmbedTypeRich,
+			Title:       trigger.EmbedTitle,
+			Description: response,
+			Color:       int(trigger.EmbedColor),
+		}
+		if trigger.EmbedImage != "" {
+			embed.Image = &discordgo.MessageEmbedImage{URL: trigger.EmbedImage}
+		}
+		send := responseSend(m, trigger, "", ref)
+		send.Embeds = []*discordgo.MessageEmbed{embed}
+		_, err := s.ChannelMessageSendComplex(m.ChannelID, send, discordgo.WithContext(ctx))
+		return err
+
+	case models.ActionSticker:
+		log.InfoContext(ctx, "Sending sticker")
+		send := responseSend(m, trigger, "", ref)
+		send.StickerIDs = []string{strings.TrimSpace(response)}
+		_, err := s.ChannelMessageSendComplex(m.ChannelID, send, discordgo.WithContext(ctx))
+		return err
+
+	case models.ActionThread:
+		log.InfoContext(ctx, "Starting thread")
+		thread, err := s.MessageThreadStartComplex(m.ChannelID, m.ID, &discordgo.ThreadStart{
+			Name:                threadName(trigger, m.Content),
+			AutoArchiveDuration: 1440,
+		}, discordgo.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		_, err = s.ChannelMessageSendComplex(thread.ID, responseSend(m, trigger, response, nil), discordgo.WithContext(ctx))
+		return err
+
+	case models.ActionDelete:
+		log.InfoContext(ctx, "Deleting trigger message")
+		if err := s.ChannelMessageDelete(m.ChannelID, m.ID, discordgo.WithContext(ctx)); err != nil {
+			return err
+		}
+		if strings.TrimSpace(response) == "" {
+			return nil
+		}
+		// The trigger is gone, so the notice cannot reply to it
+		_, err := s.ChannelMessageSendComplex(m.ChannelID, responseSend(m, trigger, response, nil), discordgo.WithContext(ctx))
+		return err
+	}
+
+	if ref != nil {
+		log.InfoContext(ctx, "Sending message reply")
+	} else {
+		log.InfoContext(ctx, "Sending message")
+	}
+	_, err := s.ChannelMessageSendComplex(m.ChannelID, responseSend(m, trigger, response, ref), discordgo.WithContext(ctx))
+	return err
+}
+
+// responseSend builds a message sent in response to the triggering message.
+// Responses that substitute text written by the sender, such as capture groups,
+// may only mention the sender and never everyone, here or any roles. Responses
+// written entirely by the admins mention whoever they name.
+func responseSend(m *discordgo.MessageCreate, trigger triggers.Trigger, content string, ref *discordgo.MessageReference) *discordgo.MessageSend {
+	ret := &discordgo.MessageSend{
+		Content:   content,
+		Reference: ref,
+	}
+	if trigger.Template.SenderControlled() {
+		ret.AllowedMentions = &discordgo.MessageAllowedMentions{
+			Parse:       []discordgo.AllowedMentionType{},
+			Users:       []string{m.Author.ID},
+			RepliedUser: ref != nil,
+		}
+	}
+	return ret
+}
+
+// reactionEmoji converts an emoji as it is written in a message, such as
+// "<:name:id>" for a custom emoji, into the form that reactions are added with.
+func reactionEmoji(emoji string) string {
+	emoji = strings.Trim(strings.TrimSpace(emoji), "<>")
+	emoji = strings.TrimPrefix(emoji, "a:")
+	return strings.TrimPrefix(emoji, ":")
+}
+
+// threadName returns the name of a thread started from the triggering message,
+// which defaults to the start of the message itself.
+func threadName(trigger triggers.Trigger, content string) string {
+	name := strings.TrimSpace(trigger.ThreadName)
+	if name == "" {
+		name = strings.TrimSpace(content)
+	}
+	if name == "" {
+		name = "Thread"
+	}
+
+	if runes := []rune(name); len(runes) > 100 {
+		name = string(runes[:99]) + "…"
+	}
+	return name
+}
+
+// responseVars gathers the values that may be substituted into the response to
+// the given message.
+func responseVars(s *discordgo.Session, m *discordgo.MessageCreate, trigger triggers.Trigger) response.Vars {
+	ret := response.Vars{
+		SenderID:   m.Author.ID,
+		Name:       displayName(m.Author, m.Member),
+		Now:        time.Now(),
+		Groups:     trigger.Pattern.FindStringSubmatch(m.Content),
+		GroupNames: trigger.Pattern.SubexpNames(),
+	}
+
+	// Channel names require a lookup, which is skipped unless it is needed
+	if trigger.Template.Uses(response.VarChannel) {
+		channel, err := s.State.Channel(m.ChannelID)
+		if err != nil {
+			channel, err = s.Channel(m.ChannelID)
 		}
+		if err == nil {
+			ret.Channel = channel.Name
+		}
+	}
+
+	return ret
+}
+
+// displayName returns the name that the user is shown with in the guild.
+func displayName(user *discordgo.User, member *discordgo.Member) string {
+	if member != nil && member.Nick != "" {
+		return member.Nick
+	} else if user.GlobalName != "" {
+		return user.GlobalName
 	}
+
+	return user.Username
 }
 
 // responseSeeder is used to randomize the message responses.
@@ -74,15 +283,34 @@ func (c *Commands) handleMessage(s *discordgo.Session, m *discordgo.MessageCreat
 // It is manipulated in the tests to ensure we get good results
 var responseSeeder = rand.New(rand.NewSource(time.Now().Unix()))
 
+// messageContext describes the chat message that a response is selected for.
+type messageContext struct {
+	// Sender is the author of the message, which is nil for webhook posts.
+	Sender *discordgo.User
+
+	// RoleIDs are the sender's roles in the guild, which are empty for direct
+	// messages and webhook posts.
+	RoleIDs []string
+
+	// GuildID is empty for direct messages.
+	GuildID   string
+	ChannelID string
+	Content   string
+
+	// Time is when the message was sent, which scheduled responses are
+	// evaluated against.
+	Time time.Time
+}
+
 // responseForTrigger will determine which response is sent for the given
-// triggering text.
+// triggering message.
 //
 // If multiple responses have been registered, send a random response from the
-// results.
-func (c *Commands) responseForTrigger(messages []models.Message, sender *discordgo.User, input string) string {
-	candidates := []models.Message{}
+// results, favoring those with a higher weight.
+func (c *Commands) responseForTrigger(compiled []triggers.Trigger, msg messageContext) (triggers.Trigger, bool) {
+	candidates := []triggers.Trigger{}
 
-	for _, message := range messages {
+	for _, message := range compiled {
 		// Filter out disabled messages
 		if !message.Enabled {
 			continue
@@ -92,14 +320,32 @@ func (c *Commands) responseForTrigger(messages []models.Message, sender *discord
 		if message.Sender != "" {
 			// If there's no sender (e.g. a webhook post) then we can't filter to it.
 			// Otherwise, try to match on their username
-			if sender == nil || sender.Username != message.Sender {
+			if msg.Sender == nil || msg.Sender.Username != message.Sender {
 				continue
 			}
 		}
 
+		// Filter by user and role
+		senderID := ""
+		if msg.Sender != nil {
+			senderID = msg.Sender.ID
+		}
+		if !message.TargetsMember(senderID, msg.RoleIDs) {
+			continue
+		}
+
+		// Filter by guild and channel
+		if !message.InScope(msg.GuildID, msg.ChannelID) {
+			continue
+		}
+
+		// Filter by schedule
+		if !message.ActiveAt(msg.Time, message.Location) {
+			continue
+		}
+
 		// Filter by trigger
-		re := regexp.MustCompile(message.Trigger)
-		if !re.MatchString(input) {
+		if !message.Pattern.MatchString(msg.Content) {
 			continue
 		}
 
@@ -107,9 +353,26 @@ func (c *Commands) responseForTrigger(messages []models.Message, sender *discord
 	}
 
 	if len(candidates) == 0 {
-		return ""
+		return triggers.Trigger{}, false
+	}
+
+	total := 0
+	for _, candidate := range candidates {
+		total += weight(candidate)
 	}
 
-	selected := responseSeeder.Intn(len(candidates))
-	return candidates[selected].Response
+	selected := responseSeeder.Intn(total)
+	for _, candidate := range candidates {
+		if selected -= weight(candidate); selected < 0 {
+			return candidate, true
+		}
+	}
+
+	return candidates[len(candidates)-1], true
+}
+
+// weight returns how likely the response is to be selected relative to the
+// other candidates. Messages without a weight count once.
+func weight(trigger triggers.Trigger) int {
+	return max(int(trigger.Weight), 1)
 }
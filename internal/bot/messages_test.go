@@ -1,10 +1,16 @@
 package bot
 
 import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/taiidani/no-time-to-explain/internal/db/models"
+	"github.com/taiidani/no-time-to-explain/internal/triggers"
 )
 
 func Test_responseForTrigger(t *testing.T) {
@@ -41,11 +47,47 @@ func Test_responseForTrigger(t *testing.T) {
 			Trigger:  "^ping$",
 			Response: "pong",
 		},
+		{
+			Enabled:  true,
+			Trigger:  "invalid[",
+			Response: "invalid",
+		},
+		{
+			Enabled:      true,
+			Trigger:      "^scoped$",
+			Response:     "scoped guild",
+			GuildAllow:   "100,200",
+			ChannelBlock: "201",
+		},
+		{
+			Enabled:      true,
+			Trigger:      "^channel$",
+			Response:     "scoped channel",
+			ChannelAllow: "101",
+			GuildBlock:   "300",
+		},
+		{
+			Enabled:     true,
+			Trigger:     "^targeted$",
+			Response:    "targeted",
+			TargetUsers: "1",
+			TargetRoles: "10,11",
+		},
+		{
+			Enabled:     true,
+			Trigger:     "^all roles$",
+			Response:    "all roles",
+			TargetRoles: "10,11",
+			RoleMatch:   models.RoleMatchAll,
+		},
 	}
 
 	type args struct {
-		sender *discordgo.User
-		input  string
+		sender    *discordgo.User
+		roleIDs   []string
+		guildID   string
+		channelID string
+		input     string
 	}
 	tests := []struct {
 		name     string
@@ -154,6 +196,140 @@ func Test_responseForTrigger(t *testing.T) {
 			},
 			want: "Response Baz",
 		},
+		{
+			name:     "invalid trigger skipped",
+			messages: fixtures,
+			args: args{
+				input: "invalid[",
+			},
+			want: "",
+		},
+		{
+			name:     "allowed guild",
+			messages: fixtures,
+			args: args{
+				guildID:   "200",
+				channelID: "202",
+				input:     "scoped",
+			},
+			want: "scoped guild",
+		},
+		{
+			name:     "unlisted guild",
+			messages: fixtures,
+			args: args{
+				guildID:   "300",
+				channelID: "301",
+				input:     "scoped",
+			},
+			want: "",
+		},
+		{
+			name:     "blocked channel in allowed guild",
+			messages: fixtures,
+			args: args{
+				guildID:   "200",
+				channelID: "201",
+				input:     "scoped",
+			},
+			want: "",
+		},
+		{
+			name:     "direct message outside guild allowlist",
+			messages: fixtures,
+			args: args{
+				channelID: "202",
+				input:     "scoped",
+			},
+			want: "",
+		},
+		{
+			name:     "allowed channel",
+			messages: fixtures,
+			args: args{
+				guildID:   "100",
+				channelID: "101",
+				input:     "channel",
+			},
+			want: "scoped channel",
+		},
+		{
+			name:     "unlisted channel",
+			messages: fixtures,
+			args: args{
+				guildID:   "100",
+				channelID: "102",
+				input:     "channel",
+			},
+			want: "",
+		},
+		{
+			name:     "allowed channel in blocked guild",
+			messages: fixtures,
+			args: args{
+				guildID:   "300",
+				channelID: "101",
+				input:     "channel",
+			},
+			want: "",
+		},
+		{
+			name:     "target user",
+			messages: fixtures,
+			args: args{
+				sender: &discordgo.User{ID: "1", Username: "renamed"},
+				input:  "targeted",
+			},
+			want: "targeted",
+		},
+		{
+			name:     "target any role",
+			messages: fixtures,
+			args: args{
+				sender:  &discordgo.User{ID: "2"},
+				roleIDs: []string{"9", "11"},
+				input:   "targeted",
+			},
+			want: "targeted",
+		},
+		{
+			name:     "untargeted user",
+			messages: fixtures,
+			args: args{
+				sender:  &discordgo.User{ID: "2"},
+				roleIDs: []string{"9"},
+				input:   "targeted",
+			},
+			want: "",
+		},
+		{
+			name:     "untargeted webhook",
+			messages: fixtures,
+			args: args{
+				input: "targeted",
+			},
+			want: "",
+		},
+		{
+			name:     "target all roles",
+			messages: fixtures,
+			args: args{
+				sender:  &discordgo.User{ID: "2"},
+				roleIDs: []string{"11", "10"},
+				input:   "all roles",
+			},
+			want: "all roles",
+		},
+		{
+			name:     "missing one of all roles",
+			messages: fixtures,
+			args: args{
+				sender:  &discordgo.User{ID: "2"},
+				roleIDs: []string{"10"},
+				input:   "all roles",
+			},
+			want: "",
+		},
 		{
 			name:     "disabled",
 			messages: fixtures,
@@ -172,9 +348,235 @@ func Test_responseForTrigger(t *testing.T) {
 				responseSeeder.Seed(tt.seed)
 			}
 
-			if got := c.responseForTrigger(tt.messages, tt.args.sender, tt.args.input); got != tt.want {
-				t.Errorf("responseForTrigger() = %v, want %v", got, tt.want)
+			got, _ := c.responseForTrigger(triggers.Compile(tt.messages), messageContext{
+				Sender:    tt.args.sender,
+				RoleIDs:   tt.args.roleIDs,
+				GuildID:   tt.args.guildID,
+				ChannelID: tt.args.channelID,
+				Content:   tt.args.input,
+			})
+			if got.Response != tt.want {
+				t.Errorf("responseForTrigger() = %v, want %v", got.Response, tt.want)
+			}
+		})
+	}
+}
+
+func Test_responseForTrigger_weighted(t *testing.T) {
+	compiled := triggers.Compile([]models.Message{
+		{Enabled: true, Trigger: "weighted", Response: "common", Weight: 3},
+		{Enabled: true, Trigger: "weighted", Response: "rare", Weight: 1},
+	})
+
+	c := Commands{}
+	responseSeeder.Seed(1)
+
+	counts := map[string]int{}
+	for range 4000 {
+		got, _ := c.responseForTrigger(compiled, messageContext{Content: "weighted"})
+		counts[got.Response]++
+	}
+
+	if counts["common"] < 2800 || counts["common"] > 3200 {
+		t.Errorf("responseForTrigger() selected the common response %d of 4000 times, want about 3000", counts["common"])
+	}
+}
+
+func Test_responseForTrigger_schedule(t *testing.T) {
+	date := func(value string) sql.NullTime {
+		ret, _ := time.Parse(time.DateOnly, value)
+		return sql.NullTime{Time: ret, Valid: true}
+	}
+
+	fixtures := []models.Message{
+		{
+			Enabled:     true,
+			Trigger:     "^holiday$",
+			Response:    "Happy holidays",
+			ActiveFrom:  date("2026-12-24"),
+			ActiveUntil: date("2026-12-26"),
+		},
+		{
+			Enabled:         true,
+			Trigger:         "^lunch$",
+			Response:        "Lunch time",
+			ActiveDays:      "1,2,3,4,5",
+			ActiveHourStart: 12,
+			ActiveHourEnd:   13,
+			Timezone:        "America/New_York",
+		},
+		{
+			Enabled:         true,
+			Trigger:         "^night$",
+			Response:        "Go to bed",
+			ActiveHourStart: 22,
+			ActiveHourEnd:   6,
+		},
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		time  string
+		want  string
+	}{
+		{name: "before holiday", input: "holiday", time: "2026-12-23T23:59:00Z", want: ""},
+		{name: "first day of holiday", input: "holiday", time: "2026-12-24T00:00:00Z", want: "Happy holidays"},
+		{name: "last day of holiday", input: "holiday", time: "2026-12-26T23:59:00Z", want: "Happy holidays"},
+		{name: "after holiday", input: "holiday", time: "2026-12-27T00:00:00Z", want: ""},
+		{name: "weekday lunch", input: "lunch", time: "2026-10-19T16:30:00Z", want: "Lunch time"},
+		{name: "weekday lunch in UTC", input: "lunch", time: "2026-10-19T12:30:00Z", want: ""},
+		{name: "weekend lunch", input: "lunch", time: "2026-10-18T16:30:00Z", want: ""},
+		{name: "late night", input: "night", time: "2026-10-18T23:00:00Z", want: "Go to bed"},
+		{name: "early morning", input: "night", time: "2026-10-19T05:59:00Z", want: "Go to bed"},
+		{name: "daytime", input: "night", time: "2026-10-19T06:00:00Z", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now, err := time.Parse(time.RFC3339, tt.time)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := Commands{}
+			got, _ := c.responseForTrigger(triggers.Compile(fixtures), messageContext{Content: tt.input, Time: now})
+			if got.Response != tt.want {
+				t.Errorf("responseForTrigger() = %v, want %v", got.Response, tt.want)
 			}
 		})
 	}
 }
+
+func Test_reactionEmoji(t *testing.T) {
+	tests := []struct {
+		emoji string
+		want  string
+	}{
+		{emoji: "👍", want: "👍"},
+		{emoji: " 👍\n", want: "👍"},
+		{emoji: "<:bees:123456>", want: "bees:123456"},
+		{emoji: "<a:dancing_bees:123456>", want: "dancing_bees:123456"},
+		{emoji: "bees:123456", want: "bees:123456"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.emoji, func(t *testing.T) {
+			if got := reactionEmoji(tt.emoji); got != tt.want {
+				t.Errorf("reactionEmoji() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_threadName(t *testing.T) {
+	tests := []struct {
+		name       string
+		threadName string
+		content    string
+		want       string
+	}{
+		{name: "configured", threadName: "Bees discussion", content: "bees?", want: "Bees discussion"},
+		{name: "from content", content: "  are there bees?  ", want: "are there bees?"},
+		{name: "empty", want: "Thread"},
+		{name: "truncated", content: strings.Repeat("b", 120), want: strings.Repeat("b", 99) + "…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trigger := triggers.Trigger{Message: models.Message{ThreadName: tt.threadName}}
+			if got := threadName(trigger, tt.content); got != tt.want {
+				t.Errorf("threadName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_responseSend(t *testing.T) {
+	m := &discordgo.MessageCreate{Message: &discordgo.Message{
+		ID:        "10",
+		ChannelID: "20",
+		Content:   "say @everyone @here <@&30> <@40>",
+		Author:    &discordgo.User{ID: "1", Username: "alice"},
+	}}
+
+	tests := []struct {
+		name        string
+		message     models.Message
+		ref         *discordgo.MessageReference
+		wantContent string
+		want        string
+	}{
+		{
+			name:        "capture group",
+			message:     models.Message{Trigger: `say (.*)`, Response: "{1}"},
+			wantContent: "@everyone @here <@&30> <@40>",
+			want:        `{"parse":[],"users":["1"],"replied_user":false}`,
+		},
+		{
+			name:        "capture group reply",
+			message:     models.Message{Trigger: `say (.*)`, Response: "{1}"},
+			ref:         m.Reference(),
+			wantContent: "@everyone @here <@&30> <@40>",
+			want:        `{"parse":[],"users":["1"],"replied_user":true}`,
+		},
+		{
+			name:        "static role mention",
+			message:     models.Message{Trigger: `say`, Response: "<@&30> {mention} needs a hand"},
+			wantContent: "<@&30> <@1> needs a hand",
+			want:        `null`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.message.Enabled = true
+			trigger := triggers.Compile([]models.Message{tt.message})[0]
+			response := trigger.Template.Render(responseVars(nil, m, trigger))
+
+			got := responseSend(m, trigger, response, tt.ref)
+			if got.Content != tt.wantContent {
+				t.Errorf("responseSend() content = %q, want %q", got.Content, tt.wantContent)
+			}
+
+			mentions, err := json.Marshal(got.AllowedMentions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(mentions) != tt.want {
+				t.Errorf("responseSend() allowed mentions = %s, want %s", mentions, tt.want)
+			}
+		})
+	}
+}
+
+// benchmarkMessages returns a realistic number of auto-response messages.
+func benchmarkMessages() []models.Message {
+	ret := []models.Message{}
+	for i := range 50 {
+		ret = append(ret, models.Message{
+			Enabled:  true,
+			Trigger:  fmt.Sprintf(`(?i)\b(trigger|phrase)%d\b`, i),
+			Response: fmt.Sprintf("Response %d", i),
+		})
+	}
+	return ret
+}
+
+// BenchmarkResponseForTrigger_compiled measures matching a chat message against
+// the triggers held by the index.
+func BenchmarkResponseForTrigger_compiled(b *testing.B) {
+	c := Commands{}
+	compiled := triggers.Compile(benchmarkMessages())
+
+	for b.Loop() {
+		c.responseForTrigger(compiled, messageContext{Content: "A chat message that mentions phrase49 at the end"})
+	}
+}
+
+// BenchmarkResponseForTrigger_uncompiled measures matching a chat message when
+// the triggers are compiled for every message, as they were before the index.
+func BenchmarkResponseForTrigger_uncompiled(b *testing.B) {
+	c := Commands{}
+	messages := benchmarkMessages()
+
+	for b.Loop() {
+		c.responseForTrigger(triggers.Compile(messages), messageContext{Content: "A chat message that mentions phrase49 at the end"})
+	}
+}
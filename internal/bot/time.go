@@ -72,7 +72,8 @@ func responseMessage(opts interactionState) (*discordgo.InteractionResponseData,
 	description := "Please use the fields below to set your current timezone and desired time."
 	content := ""
 	fields := []*discordgo.MessageEmbedField{}
-	if len(opts.Date) > 0 && len(opts.Time) > 0 && len(opts.TZ) > 0 {
+	rendered := len(opts.Date) > 0 && len(opts.Time) > 0 && len(opts.TZ) > 0
+	if rendered {
 		title = "Timestamp rendered!"
 		color = defaultColor
 
@@ -85,24 +86,11 @@ func responseMessage(opts interactionState) (*discordgo.InteractionResponseData,
 		content = fmt.Sprintf("<t:%d:f>", tm.Unix())
 		description = `Click to copy the below text fields. When used in a message, these values will display the time in the reader's local timezone in a format based on the example shown.
 
-Mobile users may have difficulty clicking to copy the fields. Long tap this message and select "Copy Text" to copy the ` + content + ` format to your clipboard. You may change the "f" in the text to modify the format according to the fields below.`
+Mobile users may have difficulty clicking to copy the fields. Long tap this message and select "Copy Text" to copy the ` + content + ` format to your clipboard. You may change the "f" in the text to modify the format according to the fields below.
 
-		types := []string{"d", "f", "t", "D", "T", "R", "F"}
-		for _, typ := range types {
-			fields = append(fields, &discordgo.MessageEmbedField{
-				Name:   fmt.Sprintf("<t:%d:%s>", tm.Unix(), typ),
-				Value:  fmt.Sprintf("```<t:%d:%s>```", tm.Unix(), typ),
-				Inline: true,
-			})
-		}
+Click "Share to channel" to post the timestamp for everyone instead.`
 
-		// Special case for LFG Bot
-		// Standard Go parsing format: January 2, 3:04:05PM, 2006 MST
-		fields = append(fields, &discordgo.MessageEmbedField{
-			Name:   "<#614104443797110794>",
-			Value:  fmt.Sprintf("```%s```", tm.Format("01/02/06 3:04PM MST")),
-			Inline: true,
-		})
+		fields = timestampFields(tm)
 	}
 
 	ret := &discordgo.InteractionResponseData{
@@ -129,6 +117,12 @@ Mobile users may have difficulty clicking to copy the fields. Long tap this mess
 						Label:    "Current Time",
 						Style:    discordgo.SecondaryButton,
 					},
+					discordgo.Button{
+						CustomID: shareTimeCustomID + customID.String(),
+						Label:    "Share to channel",
+						Style:    discordgo.SuccessButton,
+						Disabled: !rendered,
+					},
 				},
 			},
 		},
@@ -138,28 +132,97 @@ Mobile users may have difficulty clicking to copy the fields. Long tap this mess
 	return ret, nil
 }
 
-func parseOptions(ctx context.Context, i *discordgo.InteractionCreate) (interactionState, error) {
-	tz := defaultTimezone
+// timestampFields renders the time as each of Discord's timestamp formats, with
+// the tag to copy for each of them.
+func timestampFields(tm time.Time) []*discordgo.MessageEmbedField {
+	fields := []*discordgo.MessageEmbedField{}
+
+	types := []string{"d", "f", "t", "D", "T", "R", "F"}
+	for _, typ := range types {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   fmt.Sprintf("<t:%d:%s>", tm.Unix(), typ),
+			Value:  fmt.Sprintf("```<t:%d:%s>```", tm.Unix(), typ),
+			Inline: true,
+		})
+	}
+
+	// Special case for LFG Bot
+	// Standard Go parsing format: January 2, 3:04:05PM, 2006 MST
+	fields = append(fields, &discordgo.MessageEmbedField{
+		Name:   "<#614104443797110794>",
+		Value:  fmt.Sprintf("```%s```", tm.Format("01/02/06 3:04PM MST")),
+		Inline: true,
+	})
+
+	return fields
+}
+
+// loadTimezone returns the timezone saved under the state key, reporting
+// whether one was saved. The default timezone is returned otherwise.
+func loadTimezone(ctx context.Context, key string) (*time.Location, bool) {
 	var st state
-	key := generateStateKey(i)
-	if err := cacheClient.Get(ctx, key, &st); err == nil {
-		if tz, err = parseTimezone(st.TZ); err != nil {
-			slog.Warn("Unable to parse timezone", "tz", st.TZ, "key", key, "err", err)
-			tz = defaultTimezone
+	if err := cacheClient.Get(ctx, key, &st); err != nil || st.TZ == "" {
+		return defaultTimezone, false
+	}
+
+	tz, err := parseTimezone(st.TZ)
+	if err != nil {
+		slog.Warn("Unable to parse timezone", "tz", st.TZ, "key", key, "err", err)
+		return defaultTimezone, false
+	}
+	return tz, true
+}
+
+func parseOptions(ctx context.Context, i *discordgo.InteractionCreate) (interactionState, error) {
+	tz, _ := loadTimezone(ctx, generateStateKey(i))
+
+	// A timezone given to the command applies to this timestamp only
+	if name := commandOption(i, "tz"); name != "" {
+		var err error
+		if tz, err = parseTimezoneNear(name, tz); err != nil {
+			return interactionState{}, err
 		}
 	}
 
 	now := time.Now().In(tz)
+	if when := commandOption(i, "when"); when != "" {
+		tm, err := parseWhen(when, now)
+		if err != nil {
+			return interactionState{}, fmt.Errorf("could not understand %q: %w", when, err)
+		}
+
+		return interactionState{
+			Date: tm.Format("2006-01-02"),
+			Time: tm.Format("3:04:05 PM"),
+			TZ:   tm.Location().String(),
+		}, nil
+	}
+
 	ret := interactionState{
 		// Standard Go parsing format: January 2, 3:04:05PM, 2006 MST
 		Date: now.Format("2006-01-02"),
 		Time: now.Format("3:04:00 PM"),
-		TZ:   now.Format("MST"),
+		TZ:   tz.String(),
 	}
 
 	return ret, nil
 }
 
+// commandOption returns the value of the named string option of a slash
+// command, or "" for any other interaction.
+func commandOption(i *discordgo.InteractionCreate, name string) string {
+	if i.Type != discordgo.InteractionApplicationCommand {
+		return ""
+	}
+
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == name && opt.Type == discordgo.ApplicationCommandOptionString {
+			return opt.StringValue()
+		}
+	}
+	return ""
+}
+
 func parseTimestamp(opts interactionState) (time.Time, error) {
 	tz, err := parseTimezone(opts.TZ)
 	if err != nil {
@@ -180,24 +243,3 @@ func parseTimestamp(opts interactionState) (time.Time, error) {
 
 	return time.Time{}, fmt.Errorf("could not parse timezone. Format %q expected", formats[0])
 }
-
-func parseTimezone(tz string) (*time.Location, error) {
-	switch tz {
-	case "HST":
-		return time.FixedZone(tz, -10*60*60), nil
-	case "HDT", "AKST":
-		return time.FixedZone(tz, -9*60*60), nil
-	case "AKDT", "PST":
-		return time.FixedZone(tz, -8*60*60), nil
-	case "PDT", "MST":
-		return time.FixedZone(tz, -7*60*60), nil
-	case "MDT", "CST":
-		return time.FixedZone(tz, -6*60*60), nil
-	case "CDT", "EST":
-		return time.FixedZone(tz, -5*60*60), nil
-	case "EDT":
-		return time.FixedZone(tz, -4*60*60), nil
-	default:
-		return time.LoadLocation(tz)
-	}
-}
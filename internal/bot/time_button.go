@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"log/slog"
 	"strings"
-	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -15,8 +14,13 @@ const (
 	changeTimeCustomID      string = "time-btn"
 	nowTimeCustomID         string = "time-now"
 	changeTimeModalCustomID string = "time-modal"
+	shareTimeCustomID       string = "time-share"
+	shareTimeModalCustomID  string = "time-post"
 )
 
+// timestampFormats are the styles of Discord's timestamp tags.
+const timestampFormats = "dftDTRF"
+
 func changeTimeHandler(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
 	_, span := tracer.Start(ctx, "changeTimeHandler")
 	defer span.End()
@@ -143,13 +147,14 @@ func changeTimeSubmitHandler(ctx context.Context, s *discordgo.Session, i *disco
 	opts.Time = data.Components[1].(*discordgo.ActionsRow).Components[0].(*discordgo.TextInput).Value
 	opts.TZ = data.Components[2].(*discordgo.ActionsRow).Components[0].(*discordgo.TextInput).Value
 
-	// Validate the timezone, then persist it to the DB
-	_, err := parseTimezone(opts.TZ)
+	// Validate the timezone, then persist its IANA name to the DB
+	tz, err := parseTimezone(opts.TZ)
 	if err != nil {
 		errorMessage(s, i.Interaction, err)
 		return
 	}
-	if err := cacheClient.Set(ctx, generateStateKey(i), &state{TZ: opts.TZ}, time.Hour*24*365); err != nil {
+	opts.TZ = tz.String()
+	if err := cacheClient.Set(ctx, generateStateKey(i), &state{TZ: opts.TZ}, stateTTL); err != nil {
 		slog.WarnContext(ctx, "Could not persist updated timezone", "tz", opts.TZ, "err", err)
 	}
 
@@ -170,3 +175,141 @@ func changeTimeSubmitHandler(ctx context.Context, s *discordgo.Session, i *disco
 		return
 	}
 }
+
+func shareTimeHandler(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	_, span := tracer.Start(ctx, "shareTimeHandler")
+	defer span.End()
+
+	data := i.MessageComponentData()
+	optsJson := strings.TrimPrefix(data.CustomID, shareTimeCustomID)
+
+	opts := &interactionState{}
+	if err := json.Unmarshal([]byte(optsJson), opts); err != nil {
+		errorMessage(s, i.Interaction, fmt.Errorf("could not decode timestamp data: %w", err))
+		return
+	}
+
+	customID := strings.Builder{}
+	if err := json.NewEncoder(&customID).Encode(opts); err != nil {
+		errorMessage(s, i.Interaction, fmt.Errorf("could not encode timestamp data: %w", err))
+		return
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: shareTimeModalCustomID + customID.String(),
+			Title:    "Share to channel",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "txt-title",
+							Style:       discordgo.TextInputShort,
+							Required:    false,
+							Label:       "Title",
+							Placeholder: "Optional, such as \"Raid night\"",
+							MaxLength:   256,
+						},
+					},
+				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "txt-format",
+							Style:       discordgo.TextInputShort,
+							Required:    true,
+							Label:       "Format",
+							Value:       "F",
+							Placeholder: "One of d, f, t, D, T, R or F",
+							MinLength:   1,
+							MaxLength:   1,
+						},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		slog.Warn("Could not respond to user interaction", "err", err)
+		commandError(s, i.Interaction, err)
+		return
+	}
+}
+
+func shareTimeSubmitHandler(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	_, span := tracer.Start(ctx, "shareTimeSubmitHandler")
+	defer span.End()
+
+	data := i.ModalSubmitData()
+	optsJson := strings.TrimPrefix(data.CustomID, shareTimeModalCustomID)
+
+	opts := interactionState{}
+	if err := json.Unmarshal([]byte(optsJson), &opts); err != nil {
+		errorMessage(s, i.Interaction, fmt.Errorf("could not decode timestamp data: %w", err))
+		return
+	}
+	title := data.Components[0].(*discordgo.ActionsRow).Components[0].(*discordgo.TextInput).Value
+	format := data.Components[1].(*discordgo.ActionsRow).Components[0].(*discordgo.TextInput).Value
+
+	user := i.User
+	if i.Member != nil {
+		user = i.Member.User
+	}
+
+	msg, err := shareTimeMessage(opts, title, format, user, i.Member)
+	if err != nil {
+		errorMessage(s, i.Interaction, err)
+		return
+	}
+
+	// Respond with a new message rather than updating the ephemeral one, so
+	// that the timestamp is posted publicly
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: msg,
+	})
+	if err != nil {
+		slog.Warn("Could not respond to user share submission", "err", err)
+		commandError(s, i.Interaction, err)
+		return
+	}
+}
+
+// shareTimeMessage renders the public message that shares the timestamp in the
+// chosen format, attributed to the user who shared it.
+func shareTimeMessage(opts interactionState, title, format string, user *discordgo.User, member *discordgo.Member) (*discordgo.InteractionResponseData, error) {
+	format = strings.TrimSpace(format)
+	if len(format) != 1 || !strings.Contains(timestampFormats, format) {
+		return nil, fmt.Errorf("unknown format %q, expected one of d, f, t, D, T, R or F", format)
+	}
+
+	tm, err := parseTimestamp(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	description := fmt.Sprintf("<t:%d:%s>", tm.Unix(), format)
+	if format != "R" {
+		description += fmt.Sprintf(" (<t:%d:R>)", tm.Unix())
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       strings.TrimSpace(title),
+		Color:       defaultColor,
+		Description: description,
+		Footer:      &discordgo.MessageEmbedFooter{Text: defaultFooter},
+	}
+	if user != nil {
+		embed.Author = &discordgo.MessageEmbedAuthor{
+			Name:    "Shared by " + displayName(user, member),
+			IconURL: user.AvatarURL(""),
+		}
+	}
+
+	return &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{embed},
+		// Never ping anyone named in the title
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	}, nil
+}
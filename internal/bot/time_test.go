@@ -4,6 +4,8 @@ import (
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/bwmarrin/discordgo"
 )
 
 func Test_parseTimestamp(t *testing.T) {
@@ -161,3 +163,400 @@ func Test_parseTimestamp(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseWhen(t *testing.T) {
+	cet, err := time.LoadLocation("CET")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A Wednesday evening, after the daily reset
+	now := time.Date(2025, time.October, 15, 18, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name:  "tomorrow",
+			input: "tomorrow 8pm",
+			want:  time.Date(2025, time.October, 16, 20, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "tomorrow at",
+			input: "tomorrow at 8pm",
+			want:  time.Date(2025, time.October, 16, 20, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "later today",
+			input: "8pm",
+			want:  time.Date(2025, time.October, 15, 20, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "passed today",
+			input: "8am",
+			want:  time.Date(2025, time.October, 16, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "spaced meridiem",
+			input: "8:30 PM",
+			want:  time.Date(2025, time.October, 15, 20, 30, 0, 0, time.UTC),
+		},
+		{
+			name:  "midnight",
+			input: "midnight",
+			want:  time.Date(2025, time.October, 16, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "weekday with timezone",
+			input: "friday 19:30 CET",
+			want:  time.Date(2025, time.October, 17, 19, 30, 0, 0, cet),
+		},
+		{
+			name:  "on weekday at",
+			input: "on friday at 19:30 CET",
+			want:  time.Date(2025, time.October, 17, 19, 30, 0, 0, cet),
+		},
+		{
+			name:  "weekday later today",
+			input: "wed 9pm",
+			want:  time.Date(2025, time.October, 15, 21, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "weekday passed today",
+			input: "Wednesday 5pm",
+			want:  time.Date(2025, time.October, 22, 17, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "date",
+			input: "2025-12-25 noon",
+			want:  time.Date(2025, time.December, 25, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "relative",
+			input: "in 2 hours",
+			want:  time.Date(2025, time.October, 15, 20, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "relative compound",
+			input: "in an hour 30 minutes",
+			want:  time.Date(2025, time.October, 15, 19, 30, 0, 0, time.UTC),
+		},
+		{
+			name:  "relative compact",
+			input: "in 1h30m",
+			want:  time.Date(2025, time.October, 15, 19, 30, 0, 0, time.UTC),
+		},
+		{
+			name:  "reset with offset",
+			input: "reset +3h",
+			want:  time.Date(2025, time.October, 16, 20, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "weekly reset",
+			input: "weekly reset",
+			want:  time.Date(2025, time.October, 21, 17, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "offset",
+			input: "tomorrow 8pm -30m",
+			want:  time.Date(2025, time.October, 16, 19, 30, 0, 0, time.UTC),
+		},
+		{
+			name:    "ambiguous hour",
+			input:   "tomorrow 8",
+			wantErr: true,
+		},
+		{
+			name:    "only joining words",
+			input:   "on at",
+			wantErr: true,
+		},
+		{
+			name:    "ambiguous days",
+			input:   "friday tomorrow",
+			wantErr: true,
+		},
+		{
+			name:    "ambiguous times",
+			input:   "8pm 9pm",
+			wantErr: true,
+		},
+		{
+			name:    "ambiguous timezones",
+			input:   "8pm CET UTC",
+			wantErr: true,
+		},
+		{
+			name:    "relative with a day",
+			input:   "in 2 hours tomorrow",
+			wantErr: true,
+		},
+		{
+			name:    "reset with a time",
+			input:   "reset 8pm",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			input:   " ",
+			wantErr: true,
+		},
+		{
+			name:    "unknown word",
+			input:   "whenever",
+			wantErr: true,
+		},
+		{
+			name:    "invalid hour",
+			input:   "25:00",
+			wantErr: true,
+		},
+		{
+			name:    "invalid meridiem hour",
+			input:   "13pm",
+			wantErr: true,
+		},
+		{
+			name:    "relative without amount",
+			input:   "in soon",
+			wantErr: true,
+		},
+		{
+			name:    "invalid offset",
+			input:   "reset +3x",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseWhen(tt.input, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseWhen() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseWhen() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseTimezone(t *testing.T) {
+	tests := []struct {
+		name    string
+		tz      string
+		want    string
+		wantErr bool
+	}{
+		{name: "iana", tz: "America/New_York", want: "America/New_York"},
+		{name: "iana case", tz: "europe/london", want: "Europe/London"},
+		{name: "standard abbreviation", tz: "EST", want: "America/New_York"},
+		{name: "daylight abbreviation", tz: "PDT", want: "America/Los_Angeles"},
+		{name: "lowercase abbreviation", tz: "cet", want: "Europe/Berlin"},
+		{name: "region", tz: "Eastern", want: "America/New_York"},
+		{name: "city", tz: "London", want: "Europe/London"},
+		{name: "city with space", tz: "new york", want: "America/New_York"},
+		{name: "utc", tz: "UTC", want: "UTC"},
+		{name: "offset", tz: "UTC+2", want: "UTC+2"},
+		{name: "offset with minutes", tz: "gmt+05:30", want: "UTC+5:30"},
+		{name: "ambiguous abbreviation", tz: "CST", want: "America/Chicago"},
+		{name: "invalid offset", tz: "UTC+20", wantErr: true},
+		{name: "unknown", tz: "Nowhere", wantErr: true},
+		{name: "empty", tz: "", wantErr: true},
+		{name: "local", tz: "Local", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTimezone(tt.tz)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTimezone() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("parseTimezone() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseTimezoneNear(t *testing.T) {
+	tests := []struct {
+		name string
+		tz   string
+		home string
+		want string
+	}{
+		{name: "home uses abbreviation", tz: "CST", home: "Asia/Shanghai", want: "Asia/Shanghai"},
+		{name: "home uses summer abbreviation", tz: "IST", home: "Europe/Dublin", want: "Europe/Dublin"},
+		{name: "home does not use abbreviation", tz: "IST", home: "America/New_York", want: "Asia/Kolkata"},
+		{name: "home is not an abbreviation", tz: "London", home: "Europe/Dublin", want: "Europe/London"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home, err := time.LoadLocation(tt.home)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := parseTimezoneNear(tt.tz, home)
+			if err != nil {
+				t.Fatalf("parseTimezoneNear() error = %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("parseTimezoneNear() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseTimestamp_daylightSaving(t *testing.T) {
+	tests := []struct {
+		name string
+		opts interactionState
+		want time.Time
+		abbr string
+	}{
+		{
+			name: "before spring forward",
+			opts: interactionState{Date: "2025-03-08", Time: "12:00 PM", TZ: "EST"},
+			want: time.Date(2025, time.March, 8, 17, 0, 0, 0, time.UTC),
+			abbr: "EST",
+		},
+		{
+			name: "after spring forward",
+			opts: interactionState{Date: "2025-03-10", Time: "12:00 PM", TZ: "EST"},
+			want: time.Date(2025, time.March, 10, 16, 0, 0, 0, time.UTC),
+			abbr: "EDT",
+		},
+		{
+			name: "before fall back",
+			opts: interactionState{Date: "2025-11-01", Time: "8:00 PM", TZ: "America/New_York"},
+			want: time.Date(2025, time.November, 2, 0, 0, 0, 0, time.UTC),
+			abbr: "EDT",
+		},
+		{
+			name: "after fall back",
+			opts: interactionState{Date: "2025-11-02", Time: "8:00 PM", TZ: "America/New_York"},
+			want: time.Date(2025, time.November, 3, 1, 0, 0, 0, time.UTC),
+			abbr: "EST",
+		},
+		{
+			name: "british summer time",
+			opts: interactionState{Date: "2025-03-30", Time: "12:00 PM", TZ: "London"},
+			want: time.Date(2025, time.March, 30, 11, 0, 0, 0, time.UTC),
+			abbr: "BST",
+		},
+		{
+			name: "central european winter",
+			opts: interactionState{Date: "2025-10-26", Time: "12:00 PM", TZ: "CEST"},
+			want: time.Date(2025, time.October, 26, 11, 0, 0, 0, time.UTC),
+			abbr: "CET",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTimestamp(tt.opts)
+			if err != nil {
+				t.Fatalf("parseTimestamp() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseTimestamp() = %v, want %v", got, tt.want)
+			}
+			if abbr := got.Format("MST"); abbr != tt.abbr {
+				t.Errorf("parseTimestamp() abbreviation = %v, want %v", abbr, tt.abbr)
+			}
+		})
+	}
+}
+
+func Test_parseWhen_daylightSaving(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The evening before the clocks fall back
+	now := time.Date(2025, time.November, 1, 18, 0, 0, 0, newYork)
+
+	got, err := parseWhen("tomorrow 8pm", now)
+	if err != nil {
+		t.Fatalf("parseWhen() error = %v", err)
+	}
+	if want := time.Date(2025, time.November, 3, 1, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("parseWhen() = %v, want %v", got, want)
+	}
+}
+
+func Test_shareTimeMessage(t *testing.T) {
+	opts := interactionState{Date: "2025-10-17", Time: "8:00 PM", TZ: "UTC"}
+	user := &discordgo.User{ID: "1", Username: "alice"}
+
+	tests := []struct {
+		name            string
+		opts            interactionState
+		title           string
+		format          string
+		member          *discordgo.Member
+		wantTitle       string
+		wantDescription string
+		wantAuthor      string
+		wantErr         bool
+	}{
+		{
+			name:            "full",
+			opts:            opts,
+			format:          "F",
+			wantDescription: "<t:1760731200:F> (<t:1760731200:R>)",
+			wantAuthor:      "Shared by alice",
+		},
+		{
+			name:            "relative with title",
+			opts:            opts,
+			title:           " Raid night ",
+			format:          "R",
+			member:          &discordgo.Member{Nick: "Al"},
+			wantTitle:       "Raid night",
+			wantDescription: "<t:1760731200:R>",
+			wantAuthor:      "Shared by Al",
+		},
+		{
+			name:    "unknown format",
+			opts:    opts,
+			format:  "x",
+			wantErr: true,
+		},
+		{
+			name:    "missing time",
+			opts:    interactionState{TZ: "UTC"},
+			format:  "F",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := shareTimeMessage(tt.opts, tt.title, tt.format, user, tt.member)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("shareTimeMessage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if got.Flags&discordgo.MessageFlagsEphemeral != 0 {
+				t.Errorf("shareTimeMessage() is ephemeral")
+			}
+			embed := got.Embeds[0]
+			if embed.Title != tt.wantTitle {
+				t.Errorf("shareTimeMessage() title = %q, want %q", embed.Title, tt.wantTitle)
+			}
+			if embed.Description != tt.wantDescription {
+				t.Errorf("shareTimeMessage() description = %q, want %q", embed.Description, tt.wantDescription)
+			}
+			if embed.Author.Name != tt.wantAuthor {
+				t.Errorf("shareTimeMessage() author = %q, want %q", embed.Author.Name, tt.wantAuthor)
+			}
+		})
+	}
+}
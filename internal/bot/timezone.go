@@ -0,0 +1,156 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timezoneNames are the IANA zones that names are matched against, such as
+// "London" for Europe/London. Other IANA zones may still be given in full.
+var timezoneNames = []string{
+	"UTC",
+
+	"America/New_York", "America/Detroit", "America/Toronto", "America/Chicago",
+	"America/Winnipeg", "America/Mexico_City", "America/Denver", "America/Edmonton",
+	"America/Phoenix", "America/Los_Angeles", "America/Vancouver", "America/Anchorage",
+	"Pacific/Honolulu", "America/Halifax", "America/St_Johns", "America/Puerto_Rico",
+	"America/Havana", "America/Bogota", "America/Lima", "America/Caracas",
+	"America/Santiago", "America/Buenos_Aires", "America/Sao_Paulo",
+
+	"Europe/London", "Europe/Dublin", "Europe/Lisbon", "Europe/Madrid", "Europe/Paris",
+	"Europe/Brussels", "Europe/Amsterdam", "Europe/Berlin", "Europe/Zurich",
+	"Europe/Rome", "Europe/Vienna", "Europe/Prague", "Europe/Warsaw",
+	"Europe/Copenhagen", "Europe/Oslo", "Europe/Stockholm", "Europe/Helsinki",
+	"Europe/Athens", "Europe/Bucharest", "Europe/Kyiv", "Europe/Istanbul",
+	"Europe/Moscow",
+
+	"Africa/Casablanca", "Africa/Lagos", "Africa/Cairo", "Africa/Johannesburg",
+	"Africa/Nairobi",
+
+	"Asia/Jerusalem", "Asia/Riyadh", "Asia/Dubai", "Asia/Tehran", "Asia/Karachi",
+	"Asia/Kolkata", "Asia/Kathmandu", "Asia/Dhaka", "Asia/Bangkok", "Asia/Jakarta",
+	"Asia/Singapore", "Asia/Kuala_Lumpur", "Asia/Manila", "Asia/Shanghai",
+	"Asia/Hong_Kong", "Asia/Taipei", "Asia/Seoul", "Asia/Tokyo",
+
+	"Australia/Perth", "Australia/Darwin", "Australia/Adelaide", "Australia/Brisbane",
+	"Australia/Sydney", "Australia/Melbourne", "Australia/Hobart", "Pacific/Auckland",
+}
+
+// timezoneAliases maps common abbreviations and names onto IANA zones, keyed
+// in lowercase. Abbreviations shared by several zones, such as "CST" or "IST",
+// map onto the zone they most often mean here unless parseTimezoneNear is given
+// a home zone that uses them.
+var timezoneAliases = map[string]string{
+	"et": "America/New_York", "est": "America/New_York", "edt": "America/New_York", "eastern": "America/New_York",
+	"ct": "America/Chicago", "cst": "America/Chicago", "cdt": "America/Chicago", "central": "America/Chicago",
+	"mt": "America/Denver", "mst": "America/Denver", "mdt": "America/Denver", "mountain": "America/Denver",
+	"pt": "America/Los_Angeles", "pst": "America/Los_Angeles", "pdt": "America/Los_Angeles", "pacific": "America/Los_Angeles",
+	"akst": "America/Anchorage", "akdt": "America/Anchorage", "alaska": "America/Anchorage",
+	"hst": "Pacific/Honolulu", "hdt": "Pacific/Honolulu", "hawaii": "Pacific/Honolulu",
+	"ast": "America/Halifax", "adt": "America/Halifax", "atlantic": "America/Halifax",
+	"nst": "America/St_Johns", "ndt": "America/St_Johns", "newfoundland": "America/St_Johns",
+	"brt": "America/Sao_Paulo",
+
+	"bst": "Europe/London", "uk": "Europe/London", "british": "Europe/London",
+	"wet": "Europe/Lisbon", "west": "Europe/Lisbon",
+	"cet": "Europe/Berlin", "cest": "Europe/Berlin", "mez": "Europe/Berlin",
+	"eet": "Europe/Athens", "eest": "Europe/Athens",
+	"msk": "Europe/Moscow",
+
+	"ist": "Asia/Kolkata", "india": "Asia/Kolkata",
+	"pkt": "Asia/Karachi",
+	"sgt": "Asia/Singapore",
+	"hkt": "Asia/Hong_Kong",
+	"jst": "Asia/Tokyo",
+	"kst": "Asia/Seoul",
+
+	"awst": "Australia/Perth",
+	"acst": "Australia/Adelaide", "acdt": "Australia/Adelaide",
+	"aest": "Australia/Sydney", "aedt": "Australia/Sydney",
+	"nzst": "Pacific/Auckland", "nzdt": "Pacific/Auckland",
+}
+
+// utcOffsetPattern matches fixed offsets from UTC, such as "UTC+2" or "GMT-05:30".
+var utcOffsetPattern = regexp.MustCompile(`^(?i:utc|gmt)([+-])(\d{1,2})(?::?(\d{2}))?$`)
+
+// parseTimezone resolves an IANA zone, a common abbreviation such as "EDT", a
+// city such as "London" or an offset such as "UTC+2" into its location.
+// Abbreviations resolve to their IANA zone rather than a fixed offset, so that
+// saved timezones follow daylight saving time.
+func parseTimezone(tz string) (*time.Location, error) {
+	return parseTimezoneNear(tz, nil)
+}
+
+// parseTimezoneNear resolves the timezone as parseTimezone does, but resolves
+// abbreviations to the home zone when it uses them. A user whose home is
+// Europe/Dublin therefore gets Irish rather than India Standard Time for "IST".
+func parseTimezoneNear(tz string, home *time.Location) (*time.Location, error) {
+	name := strings.TrimSpace(tz)
+	key := strings.ToLower(strings.ReplaceAll(name, " ", "_"))
+	if key == "" {
+		return nil, fmt.Errorf("no timezone given")
+	}
+
+	if home != nil && usesAbbreviation(home, strings.ToUpper(name)) {
+		return home, nil
+	}
+
+	if alias, found := timezoneAliases[key]; found {
+		return time.LoadLocation(alias)
+	}
+
+	if match := utcOffsetPattern.FindStringSubmatch(name); match != nil {
+		return utcOffset(match)
+	}
+
+	// Match IANA names and their cities regardless of case, such as
+	// "europe/london" or "new york"
+	for _, zone := range timezoneNames {
+		_, city, _ := strings.Cut(zone, "/")
+		if strings.EqualFold(zone, key) || strings.EqualFold(city, key) {
+			return time.LoadLocation(zone)
+		}
+	}
+
+	ret, err := time.LoadLocation(name)
+	if err != nil || name == "Local" {
+		return nil, fmt.Errorf("unknown timezone %q, please use a name like \"America/New_York\" or \"London\"", tz)
+	}
+	return ret, nil
+}
+
+// usesAbbreviation reports whether the location uses the abbreviation in
+// either its winter or summer time this year.
+func usesAbbreviation(loc *time.Location, abbreviation string) bool {
+	year := time.Now().Year()
+	for _, month := range []time.Month{time.January, time.July} {
+		if name, _ := time.Date(year, month, 1, 12, 0, 0, 0, loc).Zone(); name == abbreviation {
+			return true
+		}
+	}
+	return false
+}
+
+// utcOffset returns a fixed zone for a match of utcOffsetPattern, named such
+// that parseTimezone resolves the name back to the same zone.
+func utcOffset(match []string) (*time.Location, error) {
+	hours, _ := strconv.Atoi(match[2])
+	minutes, _ := strconv.Atoi(match[3])
+	if hours > 14 || minutes > 59 {
+		return nil, fmt.Errorf("invalid UTC offset %s%s", match[1], match[2])
+	}
+
+	name := fmt.Sprintf("UTC%s%d", match[1], hours)
+	if minutes > 0 {
+		name += fmt.Sprintf(":%02d", minutes)
+	}
+
+	offset := hours*60*60 + minutes*60
+	if match[1] == "-" {
+		offset = -offset
+	}
+	return time.FixedZone(name, offset), nil
+}
@@ -0,0 +1,211 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxAutocompleteChoices is the number of choices that Discord accepts in
+// response to an autocomplete interaction.
+const maxAutocompleteChoices = 25
+
+// stateTTL is how long a saved timezone is kept after it was last set.
+const stateTTL = time.Hour * 24 * 365
+
+func timezoneHandler(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	ctx, span := tracer.Start(ctx, "timezoneHandler")
+	defer span.End()
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		errorMessage(s, i.Interaction, fmt.Errorf("please choose to set, show or clear your timezone"))
+		return
+	}
+
+	key := generateStateKey(i)
+	var content string
+	switch sub := options[0]; sub.Name {
+	case "set":
+		tz, err := parseTimezone(sub.Options[0].StringValue())
+		if err != nil {
+			errorMessage(s, i.Interaction, err)
+			return
+		}
+
+		if err := cacheClient.Set(ctx, key, &state{TZ: tz.String()}, stateTTL); err != nil {
+			errorMessage(s, i.Interaction, fmt.Errorf("could not save your timezone: %w", err))
+			return
+		}
+		content = "Your timezone is now " + describeTimezone(tz, time.Now())
+	case "show":
+		var st state
+		if err := cacheClient.Get(ctx, key, &st); err != nil || st.TZ == "" {
+			content = fmt.Sprintf("You have not saved a timezone, so %s is used. Set yours with `/timezone set`.", describeTimezone(defaultTimezone, time.Now()))
+			break
+		}
+
+		tz, err := parseTimezone(st.TZ)
+		if err != nil {
+			errorMessage(s, i.Interaction, fmt.Errorf("your saved timezone %q is no longer valid, please set it again", st.TZ))
+			return
+		}
+		content = "Your timezone is " + describeTimezone(tz, time.Now())
+	case "clear":
+		// The cache cannot delete keys, so the saved timezone is blanked instead
+		if err := cacheClient.Set(ctx, key, &state{}, stateTTL); err != nil {
+			errorMessage(s, i.Interaction, fmt.Errorf("could not clear your timezone: %w", err))
+			return
+		}
+		content = fmt.Sprintf("Your timezone has been cleared, so %s will be used.", describeTimezone(defaultTimezone, time.Now()))
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		slog.Warn("Could not respond to user message", "err", err)
+		commandError(s, i.Interaction, err)
+		return
+	}
+}
+
+// describeTimezone names the timezone along with its current abbreviation and
+// offset, such as "America/New_York (EDT, UTC-4)". Unnamed zones are named by
+// their offset.
+func describeTimezone(tz *time.Location, now time.Time) string {
+	abbreviation, offset := now.In(tz).Zone()
+
+	utc := "UTC"
+	if offset != 0 {
+		sign := "+"
+		if offset < 0 {
+			sign = "-"
+			offset = -offset
+		}
+		utc = fmt.Sprintf("UTC%s%d", sign, offset/60/60)
+		if minutes := offset / 60 % 60; minutes != 0 {
+			utc += fmt.Sprintf(":%02d", minutes)
+		}
+	}
+
+	name := tz.String()
+	if name == "" {
+		name = utc
+	}
+
+	details := []string{}
+	if abbreviation != "" && abbreviation != utc && abbreviation[0] != '+' && abbreviation[0] != '-' {
+		details = append(details, abbreviation)
+	}
+	if utc != name {
+		details = append(details, utc)
+	}
+
+	if len(details) == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", name, strings.Join(details, ", "))
+}
+
+// timezoneAutocomplete suggests the IANA zones and aliases matching what the
+// user has typed so far, listing those that start with it first. Every choice
+// is submitted as its IANA name.
+func timezoneAutocomplete(ctx context.Context, _ *discordgo.Session, i *discordgo.InteractionCreate, o *discordgo.ApplicationCommandInteractionDataOption) []*discordgo.ApplicationCommandOptionChoice {
+	_, span := tracer.Start(ctx, "timezoneAutocomplete")
+	defer span.End()
+
+	typed := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(o.StringValue()), " ", "_"))
+	now := time.Now()
+
+	// Suggest the user's saved timezone before any others
+	var saved string
+	var st state
+	if err := cacheClient.Get(ctx, generateStateKey(i), &st); err == nil {
+		if tz, err := parseTimezone(st.TZ); err == nil {
+			saved = tz.String()
+		}
+	}
+
+	type candidate struct {
+		label  string
+		zone   string
+		prefix bool
+	}
+	candidates := []candidate{}
+	add := func(label, zone string) {
+		lower := strings.ToLower(label)
+		_, city, _ := strings.Cut(lower, "/")
+		switch {
+		case typed == "":
+			candidates = append(candidates, candidate{label: label, zone: zone, prefix: zone == saved})
+		case strings.HasPrefix(lower, typed) || strings.HasPrefix(city, typed):
+			candidates = append(candidates, candidate{label: label, zone: zone, prefix: true})
+		case strings.Contains(lower, typed):
+			candidates = append(candidates, candidate{label: label, zone: zone})
+		}
+	}
+
+	for _, zone := range timezoneNames {
+		add(zone, zone)
+	}
+	if typed != "" {
+		aliases := make([]string, 0, len(timezoneAliases))
+		for alias := range timezoneAliases {
+			aliases = append(aliases, alias)
+		}
+		sort.Strings(aliases)
+
+		for _, alias := range aliases {
+			// Abbreviations are capitalised, and names are titled
+			label := strings.ToUpper(alias[:1]) + alias[1:]
+			if len(alias) <= 4 {
+				label = strings.ToUpper(alias)
+			}
+			add(label, timezoneAliases[alias])
+		}
+	}
+
+	// Typed or saved zones that are not in the list are still offered
+	extra := o.StringValue()
+	if typed == "" {
+		extra = saved
+	}
+	if tz, err := parseTimezone(extra); err == nil && !slices.Contains(timezoneNames, tz.String()) {
+		candidates = append(candidates, candidate{label: tz.String(), zone: tz.String(), prefix: true})
+	}
+
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].prefix && !candidates[b].prefix
+	})
+
+	ret := []*discordgo.ApplicationCommandOptionChoice{}
+	for _, c := range candidates {
+		if len(ret) == maxAutocompleteChoices {
+			break
+		}
+
+		tz, err := parseTimezone(c.zone)
+		if err != nil {
+			continue
+		}
+
+		name := describeTimezone(tz, now)
+		if !strings.EqualFold(c.label, c.zone) {
+			name = c.label + " → " + name
+		}
+		ret = append(ret, &discordgo.ApplicationCommandOptionChoice{Name: name, Value: c.zone})
+	}
+
+	return ret
+}
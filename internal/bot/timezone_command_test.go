@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func Test_describeTimezone(t *testing.T) {
+	summer := time.Date(2025, time.July, 1, 12, 0, 0, 0, time.UTC)
+	winter := time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		tz   string
+		loc  *time.Location
+		now  time.Time
+		want string
+	}{
+		{tz: "America/New_York", now: summer, want: "America/New_York (EDT, UTC-4)"},
+		{tz: "America/New_York", now: winter, want: "America/New_York (EST, UTC-5)"},
+		{tz: "America/St_Johns", now: winter, want: "America/St_Johns (NST, UTC-3:30)"},
+		{tz: "Asia/Kolkata", now: summer, want: "Asia/Kolkata (IST, UTC+5:30)"},
+		{tz: "Europe/London", now: winter, want: "Europe/London (GMT, UTC)"},
+		{tz: "UTC", now: summer, want: "UTC"},
+		{tz: "UTC+2", now: summer, want: "UTC+2"},
+		{tz: "UTC-0:30", now: summer, want: "UTC-0:30"},
+		{tz: "unnamed", loc: time.FixedZone("", -1800), now: summer, want: "UTC-0:30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tz, func(t *testing.T) {
+			tz := tt.loc
+			if tz == nil {
+				var err error
+				if tz, err = parseTimezone(tt.tz); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := describeTimezone(tz, tt.now); got != tt.want {
+				t.Errorf("describeTimezone() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_timezoneAutocomplete(t *testing.T) {
+	tests := []struct {
+		name      string
+		typed     string
+		saved     string
+		wantFirst string
+		wantCount int
+	}{
+		{name: "city prefix", typed: "lond", wantFirst: "Europe/London", wantCount: 1},
+		{name: "iana prefix", typed: "Europe/Lon", wantFirst: "Europe/London", wantCount: 1},
+		{name: "abbreviation", typed: "EST", wantFirst: "America/New_York", wantCount: 6},
+		{name: "alias", typed: "eastern", wantFirst: "America/New_York", wantCount: 1},
+		{name: "unlisted zone", typed: "Asia/Yangon", wantFirst: "Asia/Yangon", wantCount: 1},
+		{name: "saved zone first", saved: "Asia/Tokyo", wantFirst: "Asia/Tokyo", wantCount: maxAutocompleteChoices},
+		{name: "unlisted saved zone first", saved: "Asia/Yangon", wantFirst: "Asia/Yangon", wantCount: maxAutocompleteChoices},
+		{name: "nothing typed", wantFirst: "UTC", wantCount: maxAutocompleteChoices},
+		{name: "no matches", typed: "zzz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := cacheClient
+			cacheClient = newMemoryCache()
+			t.Cleanup(func() { cacheClient = previous })
+
+			i := &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{User: &discordgo.User{ID: "1"}}}
+			if tt.saved != "" {
+				if err := cacheClient.Set(t.Context(), generateStateKey(i), &state{TZ: tt.saved}, time.Hour); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			o := &discordgo.ApplicationCommandInteractionDataOption{
+				Name:    "zone",
+				Type:    discordgo.ApplicationCommandOptionString,
+				Value:   tt.typed,
+				Focused: true,
+			}
+			got := timezoneAutocomplete(t.Context(), nil, i, o)
+
+			if len(got) != tt.wantCount {
+				t.Fatalf("timezoneAutocomplete() returned %d choices, want %d", len(got), tt.wantCount)
+			}
+			if len(got) > 0 && got[0].Value != tt.wantFirst {
+				t.Errorf("timezoneAutocomplete() first choice = %v, want %v", got[0].Value, tt.wantFirst)
+			}
+		})
+	}
+}
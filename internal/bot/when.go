@@ -0,0 +1,355 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dailyResetHour is the hour, in UTC, of Destiny 2's daily reset at 17:00 UTC,
+// which "reset" refers to. The weekly reset happens at the same hour on
+// Tuesdays. The reset is fixed in UTC rather than following any daylight saving
+// time, so it moves by an hour in local time when the clocks change, such as
+// from 1pm to 12pm in New York.
+const dailyResetHour = 17
+
+var (
+	// clockPattern matches times of day such as "8pm", "8:30 PM" and "19:30".
+	clockPattern = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+
+	// numberPattern matches the amounts of relative times, such as "in 2 hours".
+	numberPattern = regexp.MustCompile(`^\d+$`)
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+var durationUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+}
+
+// when holds the parts of a written time as they are parsed.
+type when struct {
+	now      time.Time
+	zone     *time.Location
+	date     *time.Time
+	days     *int
+	weekday  *time.Weekday
+	hour     int
+	minute   int
+	clock    bool
+	reset    bool
+	weekly   bool
+	relative bool
+	offset   time.Duration
+}
+
+// parseWhen parses a written time such as "tomorrow at 8pm", "friday 19:30 CET",
+// "in 2 hours" or "reset +3h", relative to now and in its location unless the
+// input names another timezone.
+//
+// Times without a day are the next occurrence of that time, so "8am" in the
+// evening is tomorrow morning. Weekdays are the next occurrence of that day,
+// including today when the time has not yet passed. Hours without a minute
+// must be given "am" or "pm", as "8" could be either.
+func parseWhen(input string, now time.Time) (time.Time, error) {
+	w := when{now: now}
+
+	tokens := whenTokens(input)
+	if len(tokens) == 0 {
+		return time.Time{}, fmt.Errorf("no time given")
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		lower := strings.ToLower(token)
+
+		var err error
+		switch {
+		case lower == "at" || lower == "on":
+			// Skip the words joining days and times, as in "friday at 8pm"
+		case lower == "now":
+			err = w.setClock(now.Hour(), now.Minute())
+		case lower == "today" || lower == "tonight":
+			err = w.setDays(0)
+		case lower == "tomorrow":
+			err = w.setDays(1)
+		case lower == "noon":
+			err = w.setClock(12, 0)
+		case lower == "midnight":
+			err = w.setClock(0, 0)
+		case lower == "weekly" && i+1 < len(tokens) && strings.EqualFold(tokens[i+1], "reset"):
+			w.weekly = true
+		case lower == "reset":
+			if w.reset {
+				err = fmt.Errorf("%q is given more than once", token)
+			}
+			w.reset = true
+		case lower == "in":
+			var consumed int
+			consumed, err = w.setRelative(tokens[i+1:])
+			i += consumed
+		case strings.HasPrefix(lower, "+") || strings.HasPrefix(lower, "-"):
+			err = w.addOffset(lower)
+		default:
+			err = w.parseToken(token)
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	return w.resolve()
+}
+
+// whenTokens splits the input into words, joining any "am" or "pm" onto the
+// time before it.
+func whenTokens(input string) []string {
+	ret := []string{}
+	for _, field := range strings.Fields(strings.ReplaceAll(input, ",", " ")) {
+		lower := strings.ToLower(field)
+		if (lower == "am" || lower == "pm") && len(ret) > 0 {
+			ret[len(ret)-1] += lower
+			continue
+		}
+		ret = append(ret, field)
+	}
+	return ret
+}
+
+// parseToken parses a clock time, weekday, date or timezone.
+func (w *when) parseToken(token string) error {
+	lower := strings.ToLower(token)
+
+	if match := clockPattern.FindStringSubmatch(lower); match != nil {
+		hour, _ := strconv.Atoi(match[1])
+		minute, _ := strconv.Atoi(match[2])
+
+		switch match[3] {
+		case "":
+			if match[2] == "" {
+				return fmt.Errorf("%q is ambiguous, please add am or pm or use a 24-hour time like \"%d:00\"", token, hour)
+			}
+			if hour > 23 {
+				return fmt.Errorf("%q is not a valid time", token)
+			}
+		case "am", "pm":
+			if hour < 1 || hour > 12 {
+				return fmt.Errorf("%q is not a valid time", token)
+			}
+			hour %= 12
+			if match[3] == "pm" {
+				hour += 12
+			}
+		}
+		if minute > 59 {
+			return fmt.Errorf("%q is not a valid time", token)
+		}
+
+		return w.setClock(hour, minute)
+	}
+
+	if weekday, found := weekdays[lower]; found {
+		if w.hasDay() {
+			return fmt.Errorf("more than one day given")
+		}
+		w.weekday = &weekday
+		return nil
+	}
+
+	if date, err := time.Parse(time.DateOnly, token); err == nil {
+		if w.hasDay() {
+			return fmt.Errorf("more than one day given")
+		}
+		w.date = &date
+		return nil
+	}
+
+	if zone, err := parseTimezoneNear(token, w.now.Location()); err == nil {
+		if w.zone != nil {
+			return fmt.Errorf("more than one timezone given")
+		}
+		w.zone = zone
+		return nil
+	}
+
+	return fmt.Errorf("could not understand %q", token)
+}
+
+func (w *when) setClock(hour, minute int) error {
+	if w.clock {
+		return fmt.Errorf("more than one time of day given")
+	}
+	w.clock = true
+	w.hour, w.minute = hour, minute
+	return nil
+}
+
+// setDays sets the day to the given number of days after today.
+func (w *when) setDays(days int) error {
+	if w.hasDay() {
+		return fmt.Errorf("more than one day given")
+	}
+	w.days = &days
+	return nil
+}
+
+func (w *when) hasDay() bool {
+	return w.date != nil || w.days != nil || w.weekday != nil
+}
+
+// setRelative parses the amounts following "in", such as "2 hours" or
+// "1h30m", returning the number of tokens that it consumed.
+func (w *when) setRelative(tokens []string) (int, error) {
+	if w.relative {
+		return 0, fmt.Errorf("\"in\" is given more than once")
+	}
+	w.relative = true
+
+	consumed := 0
+	for consumed < len(tokens) {
+		lower := strings.ToLower(tokens[consumed])
+		if amount, ok := parseAmount(lower); ok {
+			w.offset += amount
+			consumed++
+			continue
+		}
+
+		if consumed+1 >= len(tokens) {
+			break
+		}
+		unit, found := durationUnits[strings.ToLower(tokens[consumed+1])]
+		if !found {
+			break
+		}
+
+		count := 0
+		switch {
+		case lower == "a" || lower == "an":
+			count = 1
+		case numberPattern.MatchString(lower):
+			count, _ = strconv.Atoi(lower)
+		default:
+			return consumed, fmt.Errorf("could not understand %q", tokens[consumed])
+		}
+		w.offset += time.Duration(count) * unit
+		consumed += 2
+	}
+
+	if consumed == 0 {
+		return 0, fmt.Errorf("\"in\" must be followed by an amount of time, such as \"in 2 hours\"")
+	}
+	return consumed, nil
+}
+
+// addOffset adds an offset such as "+3h" or "-30m" to the resolved time.
+func (w *when) addOffset(token string) error {
+	amount, ok := parseAmount(token[1:])
+	if !ok {
+		return fmt.Errorf("could not understand %q, expected an offset like \"+3h\"", token)
+	}
+
+	if token[0] == '-' {
+		amount = -amount
+	}
+	w.offset += amount
+	return nil
+}
+
+// parseAmount parses a compact duration such as "3h", "1h30m" or "2d".
+func parseAmount(value string) (time.Duration, bool) {
+	if value == "" || numberPattern.MatchString(value) {
+		return 0, false
+	}
+
+	if unit, found := durationUnits[value[len(value)-1:]]; found && unit >= 24*time.Hour {
+		count, err := strconv.Atoi(value[:len(value)-1])
+		if err != nil {
+			return 0, false
+		}
+		return time.Duration(count) * unit, true
+	}
+
+	ret, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, false
+	}
+	return ret, true
+}
+
+// resolve combines the parsed parts into a time.
+func (w *when) resolve() (time.Time, error) {
+	loc := w.now.Location()
+	if w.zone != nil {
+		loc = w.zone
+	}
+	now := w.now.In(loc)
+	absolute := w.clock || w.hasDay()
+
+	switch {
+	case w.relative && (absolute || w.reset):
+		return time.Time{}, fmt.Errorf("\"in\" cannot be combined with a day or time")
+	case w.reset && absolute:
+		return time.Time{}, fmt.Errorf("\"reset\" cannot be combined with a day or time, use an offset like \"reset +3h\" instead")
+	case w.relative:
+		return now.Add(w.offset), nil
+	case w.reset:
+		return nextReset(now, w.weekly).In(loc).Add(w.offset), nil
+	case !absolute && w.offset == 0:
+		return time.Time{}, fmt.Errorf("no day or time given")
+	}
+
+	hour, minute := now.Hour(), now.Minute()
+	if w.clock {
+		hour, minute = w.hour, w.minute
+	}
+
+	var ret time.Time
+	switch {
+	case w.date != nil:
+		ret = time.Date(w.date.Year(), w.date.Month(), w.date.Day(), hour, minute, 0, 0, loc)
+	case w.days != nil:
+		ret = time.Date(now.Year(), now.Month(), now.Day()+*w.days, hour, minute, 0, 0, loc)
+	case w.weekday != nil:
+		ret = time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, loc)
+		days := (int(*w.weekday) - int(now.Weekday()) + 7) % 7
+		if days == 0 && ret.Before(now) {
+			days = 7
+		}
+		ret = ret.AddDate(0, 0, days)
+	default:
+		ret = time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, loc)
+		if ret.Before(now.Truncate(time.Minute)) {
+			ret = ret.AddDate(0, 0, 1)
+		}
+	}
+
+	return ret.Add(w.offset), nil
+}
+
+// nextReset returns the next daily reset after now, or the next weekly reset
+// on Tuesday.
+func nextReset(now time.Time, weekly bool) time.Time {
+	now = now.UTC()
+	ret := time.Date(now.Year(), now.Month(), now.Day(), dailyResetHour, 0, 0, 0, time.UTC)
+	if !ret.After(now) {
+		ret = ret.AddDate(0, 0, 1)
+	}
+	for weekly && ret.Weekday() != time.Tuesday {
+		ret = ret.AddDate(0, 0, 1)
+	}
+	return ret
+}
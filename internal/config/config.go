@@ -0,0 +1,315 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/taiidani/no-time-to-explain/internal/db/models"
+	"go.yaml.in/yaml/v3"
+)
+
+// Version is the version of the Document format written by Export. It is
+// increased whenever a change would prevent older versions from importing it.
+const Version = 1
+
+// Supported values for the format of an encoded Document.
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// Document is the portable form of the bot's configuration, holding every
+// auto-response message and feed. It omits anything that is recorded by the bot
+// as it runs, such as database IDs, hit counts and feed health.
+type Document struct {
+	Version  int       `json:"version" yaml:"version"`
+	Messages []Message `json:"messages" yaml:"messages"`
+	Feeds    []Feed    `json:"feeds" yaml:"feeds"`
+}
+
+// Message is the portable form of an auto-response message. Messages are
+// identified by their trigger and response together.
+type Message struct {
+	Trigger  string `json:"trigger" yaml:"trigger"`
+	Response string `json:"response" yaml:"response"`
+	Enabled  bool   `json:"enabled" yaml:"enabled"`
+	Action   string `json:"action,omitempty" yaml:"action,omitempty"`
+
+	EmbedTitle string `json:"embed_title,omitempty" yaml:"embed_title,omitempty"`
+	EmbedColor int32  `json:"embed_color,omitempty" yaml:"embed_color,omitempty"`
+	EmbedImage string `json:"embed_image,omitempty" yaml:"embed_image,omitempty"`
+	ThreadName string `json:"thread_name,omitempty" yaml:"thread_name,omitempty"`
+
+	Sender      string   `json:"sender,omitempty" yaml:"sender,omitempty"`
+	TargetUsers []string `json:"target_users,omitempty" yaml:"target_users,omitempty"`
+	TargetRoles []string `json:"target_roles,omitempty" yaml:"target_roles,omitempty"`
+	RoleMatch   string   `json:"role_match,omitempty" yaml:"role_match,omitempty"`
+
+	GuildAllow   []string `json:"guild_allow,omitempty" yaml:"guild_allow,omitempty"`
+	GuildBlock   []string `json:"guild_block,omitempty" yaml:"guild_block,omitempty"`
+	ChannelAllow []string `json:"channel_allow,omitempty" yaml:"channel_allow,omitempty"`
+	ChannelBlock []string `json:"channel_block,omitempty" yaml:"channel_block,omitempty"`
+
+	ChannelCooldown int32 `json:"channel_cooldown,omitempty" yaml:"channel_cooldown,omitempty"`
+	UserCooldown    int32 `json:"user_cooldown,omitempty" yaml:"user_cooldown,omitempty"`
+	RateLimit       int32 `json:"rate_limit,omitempty" yaml:"rate_limit,omitempty"`
+
+	Weight          int32    `json:"weight,omitempty" yaml:"weight,omitempty"`
+	ActiveFrom      string   `json:"active_from,omitempty" yaml:"active_from,omitempty"`
+	ActiveUntil     string   `json:"active_until,omitempty" yaml:"active_until,omitempty"`
+	ActiveDays      []string `json:"active_days,omitempty" yaml:"active_days,omitempty"`
+	ActiveHourStart int32    `json:"active_hour_start,omitempty" yaml:"active_hour_start,omitempty"`
+	ActiveHourEnd   int32    `json:"active_hour_end,omitempty" yaml:"active_hour_end,omitempty"`
+	Timezone        string   `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+}
+
+// Feed is the portable form of a feed. Feeds are identified by their source and
+// author together. Replies and quotes are included unless turned off, as they
+// are for feeds created in the admin page, so only false is written for them.
+type Feed struct {
+	Source         string   `json:"source" yaml:"source"`
+	Author         string   `json:"author" yaml:"author"`
+	AuthorSourceID string   `json:"author_source_id,omitempty" yaml:"author_source_id,omitempty"`
+	ChannelID      string   `json:"channel_id,omitempty" yaml:"channel_id,omitempty"`
+	RichEmbed      bool     `json:"rich_embed,omitempty" yaml:"rich_embed,omitempty"`
+	IncludeReplies *bool    `json:"include_replies,omitempty" yaml:"include_replies,omitempty"`
+	IncludeQuotes  *bool    `json:"include_quotes,omitempty" yaml:"include_quotes,omitempty"`
+	IncludeReposts bool     `json:"include_reposts,omitempty" yaml:"include_reposts,omitempty"`
+	MediaOnly      bool     `json:"media_only,omitempty" yaml:"media_only,omitempty"`
+	KeywordAllow   []string `json:"keyword_allow,omitempty" yaml:"keyword_allow,omitempty"`
+	KeywordBlock   []string `json:"keyword_block,omitempty" yaml:"keyword_block,omitempty"`
+	Mirror         string   `json:"mirror,omitempty" yaml:"mirror,omitempty"`
+}
+
+// Key identifies the message within a Document.
+func (m Message) Key() string {
+	return fmt.Sprintf("%s -> %s", m.Trigger, m.Response)
+}
+
+// Key identifies the feed within a Document.
+func (f Feed) Key() string {
+	return fmt.Sprintf("%s %s", f.Source, f.Author)
+}
+
+// Export loads every message and feed into a Document.
+func Export(ctx context.Context, queries *models.Queries) (Document, error) {
+	ret := Document{Version: Version, Messages: []Message{}, Feeds: []Feed{}}
+
+	messages, err := queries.LoadMessages(ctx)
+	if err != nil {
+		return ret, fmt.Errorf("could not load messages: %w", err)
+	}
+	for _, message := range messages {
+		ret.Messages = append(ret.Messages, fromMessage(message))
+	}
+
+	feeds, err := queries.LoadFeeds(ctx)
+	if err != nil {
+		return ret, fmt.Errorf("could not load feeds: %w", err)
+	}
+	for _, feed := range feeds {
+		ret.Feeds = append(ret.Feeds, fromFeed(feed))
+	}
+
+	return ret, nil
+}
+
+// Encode writes the document in the given format.
+func Encode(w io.Writer, doc Document, format string) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// Decode reads a document in either format, as JSON documents are also valid
+// YAML. Documents written by a newer version of the bot are rejected.
+func Decode(r io.Reader) (Document, error) {
+	var ret Document
+
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&ret); err != nil {
+		return ret, fmt.Errorf("could not decode document: %w", err)
+	}
+
+	if ret.Version < 1 || ret.Version > Version {
+		return ret, fmt.Errorf("unsupported document version %d, expected %d", ret.Version, Version)
+	}
+
+	return ret, nil
+}
+
+func fromMessage(m models.Message) Message {
+	ret := Message{
+		Trigger:  m.Trigger,
+		Response: m.Response,
+		Enabled:  m.Enabled,
+		Action:   m.Action,
+
+		EmbedTitle: m.EmbedTitle,
+		EmbedColor: m.EmbedColor,
+		EmbedImage: m.EmbedImage,
+		ThreadName: m.ThreadName,
+
+		Sender:      m.Sender,
+		TargetUsers: list(m.TargetUserIDs()),
+		TargetRoles: list(m.TargetRoleIDs()),
+		RoleMatch:   m.RoleMatch,
+
+		GuildAllow:   list(m.GuildAllowIDs()),
+		GuildBlock:   list(m.GuildBlockIDs()),
+		ChannelAllow: list(m.ChannelAllowIDs()),
+		ChannelBlock: list(m.ChannelBlockIDs()),
+
+		ChannelCooldown: m.ChannelCooldown,
+		UserCooldown:    m.UserCooldown,
+		RateLimit:       m.RateLimit,
+
+		Weight:          m.Weight,
+		ActiveDays:      list(m.ActiveDayList()),
+		ActiveHourStart: m.ActiveHourStart,
+		ActiveHourEnd:   m.ActiveHourEnd,
+		Timezone:        m.Timezone,
+	}
+
+	if m.ActiveFrom.Valid {
+		ret.ActiveFrom = m.ActiveFrom.Time.Format(time.DateOnly)
+	}
+	if m.ActiveUntil.Valid {
+		ret.ActiveUntil = m.ActiveUntil.Time.Format(time.DateOnly)
+	}
+
+	return ret
+}
+
+// model converts the message into its database row, leaving the fields that
+// the bot records as it runs empty.
+func (m Message) model() (models.Message, error) {
+	ret := models.Message{
+		Trigger:  m.Trigger,
+		Response: m.Response,
+		Enabled:  m.Enabled,
+		Action:   m.Action,
+
+		EmbedTitle: m.EmbedTitle,
+		EmbedColor: m.EmbedColor,
+		EmbedImage: m.EmbedImage,
+		ThreadName: m.ThreadName,
+
+		Sender:      m.Sender,
+		TargetUsers: models.JoinIDs(m.TargetUsers),
+		TargetRoles: models.JoinIDs(m.TargetRoles),
+		RoleMatch:   m.RoleMatch,
+
+		GuildAllow:   models.JoinIDs(m.GuildAllow),
+		GuildBlock:   models.JoinIDs(m.GuildBlock),
+		ChannelAllow: models.JoinIDs(m.ChannelAllow),
+		ChannelBlock: models.JoinIDs(m.ChannelBlock),
+
+		ChannelCooldown: m.ChannelCooldown,
+		UserCooldown:    m.UserCooldown,
+		RateLimit:       m.RateLimit,
+
+		Weight:          max(m.Weight, 1),
+		ActiveDays:      models.JoinIDs(m.ActiveDays),
+		ActiveHourStart: m.ActiveHourStart,
+		ActiveHourEnd:   m.ActiveHourEnd,
+		Timezone:        m.Timezone,
+	}
+
+	var err error
+	if ret.ActiveFrom, err = parseDate(m.ActiveFrom); err != nil {
+		return ret, fmt.Errorf("invalid active_from: %w", err)
+	}
+	if ret.ActiveUntil, err = parseDate(m.ActiveUntil); err != nil {
+		return ret, fmt.Errorf("invalid active_until: %w", err)
+	}
+
+	return ret, nil
+}
+
+// list returns nil for an empty list, so that it is left out of the document
+// and is decoded back to nil.
+func list(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+// unlessOn returns nil for a setting that is on by default and is on, so that
+// only the settings turned off are written.
+func unlessOn(value bool) *bool {
+	if value {
+		return nil
+	}
+	return &value
+}
+
+// isOn reports whether a setting that is on by default is on.
+func isOn(value *bool) bool {
+	return value == nil || *value
+}
+
+func parseDate(value string) (sql.NullTime, error) {
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+
+	ret, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	return sql.NullTime{Time: ret, Valid: true}, nil
+}
+
+func fromFeed(f models.Feed) Feed {
+	return Feed{
+		Source:         f.Source,
+		Author:         f.Author,
+		AuthorSourceID: f.AuthorSourceID,
+		ChannelID:      f.ChannelID,
+		RichEmbed:      f.RichEmbed,
+		IncludeReplies: unlessOn(f.IncludeReplies),
+		IncludeQuotes:  unlessOn(f.IncludeQuotes),
+		IncludeReposts: f.IncludeReposts,
+		MediaOnly:      f.MediaOnly,
+		KeywordAllow:   list(f.KeywordAllowPatterns()),
+		KeywordBlock:   list(f.KeywordBlockPatterns()),
+		Mirror:         f.Mirror,
+	}
+}
+
+// apply copies the feed's settings onto its database row, leaving the fields
+// that the bot records as it runs untouched.
+func (f Feed) apply(row *models.Feed) {
+	row.Source = f.Source
+	row.Author = f.Author
+	row.AuthorSourceID = f.AuthorSourceID
+	row.ChannelID = f.ChannelID
+	row.RichEmbed = f.RichEmbed
+	row.IncludeReplies = isOn(f.IncludeReplies)
+	row.IncludeQuotes = isOn(f.IncludeQuotes)
+	row.IncludeReposts = f.IncludeReposts
+	row.MediaOnly = f.MediaOnly
+	row.KeywordAllow = strings.Join(f.KeywordAllow, "\n")
+	row.KeywordBlock = strings.Join(f.KeywordBlock, "\n")
+	row.Mirror = f.Mirror
+}
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"bytes"
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/taiidani/no-time-to-explain/internal/db/models"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	message := models.Message{
+		Trigger:      "^ping$",
+		Response:     "pong",
+		Enabled:      true,
+		ChannelAllow: "1,2",
+		Weight:       2,
+		ActiveFrom:   sql.NullTime{Time: time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC), Valid: true},
+	}
+	feed := models.Feed{
+		Source:         models.SourceBluesky,
+		Author:         "example.bsky.social",
+		AuthorSourceID: "did:plc:example",
+		KeywordAllow:   "bees\nwasps",
+	}
+	doc := Document{
+		Version:  Version,
+		Messages: []Message{fromMessage(message)},
+		Feeds:    []Feed{fromFeed(feed)},
+	}
+
+	for _, format := range []string{FormatYAML, FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			buf := bytes.Buffer{}
+			if err := Encode(&buf, doc, format); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+
+			got, err := Decode(&buf)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, doc) {
+				t.Errorf("Decode() = %+v, want %+v", got, doc)
+			}
+
+			row, err := got.Messages[0].model()
+			if err != nil {
+				t.Fatalf("model() error = %v", err)
+			}
+			if row.ChannelAllow != message.ChannelAllow || !row.ActiveFrom.Time.Equal(message.ActiveFrom.Time) {
+				t.Errorf("model() = %+v, want %+v", row, message)
+			}
+
+			var gotFeed models.Feed
+			got.Feeds[0].apply(&gotFeed)
+			if gotFeed.KeywordAllow != feed.KeywordAllow {
+				t.Errorf("apply() KeywordAllow = %q, want %q", gotFeed.KeywordAllow, feed.KeywordAllow)
+			}
+		})
+	}
+}
+
+func TestEncodeDecode_feedDefaults(t *testing.T) {
+	tests := []struct {
+		name string
+		feed models.Feed
+		want string
+	}{
+		{
+			name: "defaults",
+			feed: models.Feed{Source: models.SourceRSS, Author: "https://example.com/feed", IncludeReplies: true, IncludeQuotes: true},
+		},
+		{
+			name: "turned off",
+			feed: models.Feed{Source: models.SourceRSS, Author: "https://example.com/feed"},
+			want: `"include_replies":false,"include_quotes":false`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := bytes.Buffer{}
+			doc := Document{Version: Version, Feeds: []Feed{fromFeed(tt.feed)}}
+			if err := Encode(&buf, doc, FormatJSON); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+
+			encoded := strings.Join(strings.Fields(buf.String()), "")
+			if tt.want == "" && strings.Contains(encoded, "include_") {
+				t.Errorf("Encode() = %s, want the defaults omitted", encoded)
+			} else if !strings.Contains(encoded, tt.want) {
+				t.Errorf("Encode() = %s, want it to contain %s", encoded, tt.want)
+			}
+
+			got, err := Decode(&buf)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+
+			var row models.Feed
+			got.Feeds[0].apply(&row)
+			if row.IncludeReplies != tt.feed.IncludeReplies || row.IncludeQuotes != tt.feed.IncludeQuotes {
+				t.Errorf("apply() = replies %v quotes %v, want replies %v quotes %v",
+					row.IncludeReplies, row.IncludeQuotes, tt.feed.IncludeReplies, tt.feed.IncludeQuotes)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		wantErr string
+	}{
+		{
+			name: "yaml",
+			doc:  "version: 1\nmessages:\n  - trigger: ping\n    response: pong\n",
+		},
+		{
+			name: "json",
+			doc:  `{"version": 1, "feeds": [{"source": "rss", "author": "https://example.com/feed"}]}`,
+		},
+		{
+			name:    "newer version",
+			doc:     "version: 2\n",
+			wantErr: "unsupported document version 2",
+		},
+		{
+			name:    "missing version",
+			doc:     "messages: []\n",
+			wantErr: "unsupported document version 0",
+		},
+		{
+			name:    "unknown field",
+			doc:     "version: 1\nmessages:\n  - trigger: ping\n    reply: pong\n",
+			wantErr: "field reply not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(strings.NewReader(tt.doc))
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Decode() error = %v", err)
+			} else if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Decode() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_plan(t *testing.T) {
+	on := true
+	current := Document{
+		Version: Version,
+		Messages: []Message{
+			{Trigger: "ping", Response: "pong", Enabled: true, Weight: 1},
+			{Trigger: "bees", Response: "buzz", Enabled: true, Weight: 1},
+			{Trigger: "old", Response: "gone", Weight: 1},
+		},
+		Feeds: []Feed{
+			{Source: "bluesky", Author: "example.bsky.social", AuthorSourceID: "did:plc:example"},
+		},
+	}
+	incoming := Document{
+		Version: Version,
+		Messages: []Message{
+			{Trigger: "ping", Response: "pong", Enabled: true},
+			{Trigger: "bees", Response: "buzz", Enabled: false, ChannelAllow: []string{"1"}, Weight: 1},
+			{Trigger: "new", Response: "hello", Enabled: true},
+		},
+		Feeds: []Feed{
+			{Source: "bluesky", Author: "example.bsky.social", IncludeReplies: &on},
+			{Source: "rss", Author: "https://example.com/feed", RichEmbed: true},
+		},
+	}
+
+	got, err := plan(current, incoming)
+	if err != nil {
+		t.Fatalf("plan() error = %v", err)
+	}
+
+	want := []Change{
+		{Kind: KindMessage, Key: "bees -> buzz", Action: ActionUpdate, Fields: []string{"channel_allow", "enabled"}},
+		{Kind: KindMessage, Key: "new -> hello", Action: ActionCreate},
+		{Kind: KindMessage, Key: "old -> gone", Action: ActionUntracked},
+		{Kind: KindMessage, Key: "ping -> pong", Action: ActionUnchanged, Fields: []string{}},
+		{Kind: KindFeed, Key: "bluesky example.bsky.social", Action: ActionUnchanged, Fields: []string{}},
+		{Kind: KindFeed, Key: "rss https://example.com/feed", Action: ActionCreate},
+	}
+	if !reflect.DeepEqual(got.Changes, want) {
+		t.Errorf("plan() = %+v, want %+v", got.Changes, want)
+	}
+	if got.Count(ActionCreate) != 2 {
+		t.Errorf("Count(create) = %d, want 2", got.Count(ActionCreate))
+	}
+}
+
+func Test_plan_duplicates(t *testing.T) {
+	incoming := Document{
+		Version: Version,
+		Messages: []Message{
+			{Trigger: "ping", Response: "pong"},
+			{Trigger: "ping", Response: "pong", Enabled: true},
+		},
+	}
+
+	if _, err := plan(Document{}, incoming); err == nil || !strings.Contains(err.Error(), "more than once") {
+		t.Errorf("plan() error = %v, want a duplicate error", err)
+	}
+}
@@ -0,0 +1,397 @@
+package config
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/taiidani/no-time-to-explain/internal/db/models"
+)
+
+// Kinds of rows that a Change applies to.
+const (
+	KindMessage = "message"
+	KindFeed    = "feed"
+)
+
+// Supported values for `Change.Action`.
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionUnchanged = "unchanged"
+
+	// ActionUntracked rows exist in the database but not in the document.
+	// Imports never delete them, so they must be removed through the admin
+	// page if they are no longer wanted.
+	ActionUntracked = "untracked"
+)
+
+// Change describes what an import does to a single message or feed.
+type Change struct {
+	Kind   string
+	Key    string
+	Action string
+
+	// Fields are the names of the fields that an update changes.
+	Fields []string
+}
+
+// Diff is the outcome of an import, listing a Change for every message and
+// feed in either the database or the document.
+type Diff struct {
+	Changes []Change
+}
+
+// Count returns the number of changes with the given action.
+func (d Diff) Count(action string) int {
+	ret := 0
+	for _, change := range d.Changes {
+		if change.Action == action {
+			ret++
+		}
+	}
+	return ret
+}
+
+// Import upserts the messages and feeds of the document into the database,
+// creating those that do not exist and updating those that do. Nothing is
+// written if any of them are invalid, or if dryRun is set, in which case the
+// returned Diff shows what the import would have done.
+func Import(ctx context.Context, conn *sql.DB, doc Document, dryRun bool) (Diff, error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return Diff{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	queries := models.New(conn).WithTx(tx)
+
+	messages, err := queries.LoadMessages(ctx)
+	if err != nil {
+		return Diff{}, fmt.Errorf("could not load messages: %w", err)
+	}
+	feeds, err := queries.LoadFeeds(ctx)
+	if err != nil {
+		return Diff{}, fmt.Errorf("could not load feeds: %w", err)
+	}
+
+	current := Document{Version: Version}
+	messageRows := map[string]models.Message{}
+	for _, message := range messages {
+		exported := fromMessage(message)
+		current.Messages = append(current.Messages, exported)
+		messageRows[exported.Key()] = message
+	}
+	feedRows := map[string]models.Feed{}
+	for _, feed := range feeds {
+		exported := fromFeed(feed)
+		current.Feeds = append(current.Feeds, exported)
+		feedRows[exported.Key()] = feed
+	}
+
+	diff, err := plan(current, doc)
+	if err != nil {
+		return diff, err
+	}
+
+	var errs error
+	for _, message := range doc.Messages {
+		key := message.Key()
+		change := diff.find(KindMessage, key)
+		if change.Action == ActionUnchanged {
+			continue
+		}
+
+		row, err := message.model()
+		if err == nil {
+			err = queries.ValidateMessage(row)
+		}
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("message %q: %w", key, err))
+			continue
+		}
+
+		if change.Action == ActionCreate {
+			err = createMessage(ctx, queries, row)
+		} else {
+			row.ID = messageRows[key].ID
+			err = updateMessage(ctx, queries, row)
+		}
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("message %q: %w", key, err))
+		}
+	}
+
+	for _, feed := range doc.Feeds {
+		key := feed.Key()
+		change := diff.find(KindFeed, key)
+		if change.Action == ActionUnchanged {
+			continue
+		}
+
+		row, found := feedRows[key]
+		if !found {
+			// Start new feeds from now, rather than relaying their history
+			row.LastMessage = time.Now()
+		}
+		feed.apply(&row)
+
+		if err := queries.ValidateFeed(&row); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("feed %q: %w", key, err))
+			continue
+		}
+
+		if err := saveFeed(ctx, queries, row); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("feed %q: %w", key, err))
+		}
+	}
+
+	if errs != nil || dryRun {
+		return diff, errs
+	}
+
+	return diff, tx.Commit()
+}
+
+// plan compares the document being imported against the current document,
+// matching messages and feeds by their keys.
+func plan(current Document, incoming Document) (Diff, error) {
+	ret := Diff{}
+
+	currentMessages := map[string]Message{}
+	for _, message := range current.Messages {
+		currentMessages[message.Key()] = message
+	}
+	currentFeeds := map[string]Feed{}
+	for _, feed := range current.Feeds {
+		currentFeeds[feed.Key()] = feed
+	}
+
+	var errs error
+	seen := map[string]bool{}
+	for _, message := range incoming.Messages {
+		key := message.Key()
+		if seen[KindMessage+key] {
+			errs = errors.Join(errs, fmt.Errorf("message %q appears more than once", key))
+			continue
+		}
+		seen[KindMessage+key] = true
+
+		// Messages without a weight are imported with the default weight
+		message.Weight = max(message.Weight, 1)
+
+		existing, found := currentMessages[key]
+		ret.Changes = append(ret.Changes, compare(KindMessage, key, existing, message, found))
+	}
+	for _, feed := range incoming.Feeds {
+		key := feed.Key()
+		if seen[KindFeed+key] {
+			errs = errors.Join(errs, fmt.Errorf("feed %q appears more than once", key))
+			continue
+		}
+		seen[KindFeed+key] = true
+
+		// Settings that are on by default are compared as they are exported
+		feed.IncludeReplies = unlessOn(isOn(feed.IncludeReplies))
+		feed.IncludeQuotes = unlessOn(isOn(feed.IncludeQuotes))
+
+		existing, found := currentFeeds[key]
+		// The source ID is resolved from the author, so is only compared if
+		// the document specifies it
+		if feed.AuthorSourceID == "" {
+			existing.AuthorSourceID = ""
+		}
+		ret.Changes = append(ret.Changes, compare(KindFeed, key, existing, feed, found))
+	}
+
+	for key := range currentMessages {
+		if !seen[KindMessage+key] {
+			ret.Changes = append(ret.Changes, Change{Kind: KindMessage, Key: key, Action: ActionUntracked})
+		}
+	}
+	for key := range currentFeeds {
+		if !seen[KindFeed+key] {
+			ret.Changes = append(ret.Changes, Change{Kind: KindFeed, Key: key, Action: ActionUntracked})
+		}
+	}
+
+	slices.SortStableFunc(ret.Changes, func(a, b Change) int {
+		if a.Kind != b.Kind {
+			// Messages are listed before feeds, as they are in the document
+			return -cmp.Compare(a.Kind, b.Kind)
+		}
+		return cmp.Compare(a.Key, b.Key)
+	})
+
+	return ret, errs
+}
+
+// compare describes the change from the existing row to the incoming one.
+func compare(kind, key string, existing, incoming any, found bool) Change {
+	ret := Change{Kind: kind, Key: key, Action: ActionCreate}
+	if !found {
+		return ret
+	}
+
+	ret.Fields = changedFields(existing, incoming)
+	ret.Action = ActionUpdate
+	if len(ret.Fields) == 0 {
+		ret.Action = ActionUnchanged
+	}
+	return ret
+}
+
+// changedFields returns the names of the fields that differ between the two
+// values, as they are named in the document.
+func changedFields(a, b any) []string {
+	left, right := fieldMap(a), fieldMap(b)
+
+	ret := []string{}
+	for name := range left {
+		if !reflect.DeepEqual(left[name], right[name]) {
+			ret = append(ret, name)
+		}
+	}
+	for name := range right {
+		if _, found := left[name]; !found {
+			ret = append(ret, name)
+		}
+	}
+
+	slices.Sort(ret)
+	return ret
+}
+
+func fieldMap(value any) map[string]any {
+	ret := map[string]any{}
+	data, _ := json.Marshal(value)
+	_ = json.Unmarshal(data, &ret)
+	return ret
+}
+
+func (d Diff) find(kind, key string) Change {
+	for _, change := range d.Changes {
+		if change.Kind == kind && change.Key == key {
+			return change
+		}
+	}
+	return Change{}
+}
+
+func createMessage(ctx context.Context, queries *models.Queries, m models.Message) error {
+	_, err := queries.CreateMessage(ctx, models.CreateMessageParams{
+		Enabled:  m.Enabled,
+		Sender:   m.Sender,
+		Trigger:  m.Trigger,
+		Response: m.Response,
+
+		GuildAllow:   m.GuildAllow,
+		GuildBlock:   m.GuildBlock,
+		ChannelAllow: m.ChannelAllow,
+		ChannelBlock: m.ChannelBlock,
+
+		ChannelCooldown: m.ChannelCooldown,
+		UserCooldown:    m.UserCooldown,
+		RateLimit:       m.RateLimit,
+
+		Weight:          m.Weight,
+		ActiveFrom:      m.ActiveFrom,
+		ActiveUntil:     m.ActiveUntil,
+		ActiveDays:      m.ActiveDays,
+		ActiveHourStart: m.ActiveHourStart,
+		ActiveHourEnd:   m.ActiveHourEnd,
+		Timezone:        m.Timezone,
+
+		Action:     m.Action,
+		EmbedTitle: m.EmbedTitle,
+		EmbedColor: m.EmbedColor,
+		EmbedImage: m.EmbedImage,
+		ThreadName: m.ThreadName,
+
+		TargetUsers: m.TargetUsers,
+		TargetRoles: m.TargetRoles,
+		RoleMatch:   m.RoleMatch,
+	})
+	return err
+}
+
+func updateMessage(ctx context.Context, queries *models.Queries, m models.Message) error {
+	_, err := queries.UpdateMessage(ctx, models.UpdateMessageParams{
+		ID:       m.ID,
+		Enabled:  m.Enabled,
+		Sender:   m.Sender,
+		Trigger:  m.Trigger,
+		Response: m.Response,
+
+		GuildAllow:   m.GuildAllow,
+		GuildBlock:   m.GuildBlock,
+		ChannelAllow: m.ChannelAllow,
+		ChannelBlock: m.ChannelBlock,
+
+		ChannelCooldown: m.ChannelCooldown,
+		UserCooldown:    m.UserCooldown,
+		RateLimit:       m.RateLimit,
+
+		Weight:          m.Weight,
+		ActiveFrom:      m.ActiveFrom,
+		ActiveUntil:     m.ActiveUntil,
+		ActiveDays:      m.ActiveDays,
+		ActiveHourStart: m.ActiveHourStart,
+		ActiveHourEnd:   m.ActiveHourEnd,
+		Timezone:        m.Timezone,
+
+		Action:     m.Action,
+		EmbedTitle: m.EmbedTitle,
+		EmbedColor: m.EmbedColor,
+		EmbedImage: m.EmbedImage,
+		ThreadName: m.ThreadName,
+
+		TargetUsers: m.TargetUsers,
+		TargetRoles: m.TargetRoles,
+		RoleMatch:   m.RoleMatch,
+	})
+	return err
+}
+
+// saveFeed creates or updates the feed. New feeds are created with their
+// identity first, then updated with the rest of their settings. Existing feeds
+// keep their last message, which only the refreshes advance.
+func saveFeed(ctx context.Context, queries *models.Queries, f models.Feed) error {
+	if f.ID == 0 {
+		created, err := queries.CreateFeed(ctx, models.CreateFeedParams{
+			Source:         f.Source,
+			Author:         f.Author,
+			AuthorSourceID: f.AuthorSourceID,
+			LastMessage:    f.LastMessage,
+			ChannelID:      f.ChannelID,
+			RichEmbed:      f.RichEmbed,
+		})
+		if err != nil {
+			return err
+		}
+		f.ID = created.ID
+	}
+
+	_, err := queries.UpdateFeedSettings(ctx, models.UpdateFeedSettingsParams{
+		ID:             f.ID,
+		Source:         f.Source,
+		Author:         f.Author,
+		AuthorSourceID: f.AuthorSourceID,
+		ChannelID:      f.ChannelID,
+		RichEmbed:      f.RichEmbed,
+		IncludeReplies: f.IncludeReplies,
+		IncludeQuotes:  f.IncludeQuotes,
+		IncludeReposts: f.IncludeReposts,
+		MediaOnly:      f.MediaOnly,
+		KeywordAllow:   f.KeywordAllow,
+		KeywordBlock:   f.KeywordBlock,
+		Mirror:         f.Mirror,
+	})
+	return err
+}
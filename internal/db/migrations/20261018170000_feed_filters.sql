@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE feed ADD COLUMN include_replies BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE feed ADD COLUMN include_quotes BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE feed ADD COLUMN include_reposts BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE feed ADD COLUMN media_only BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE feed ADD COLUMN keyword_allow TEXT NOT NULL DEFAULT '';
+ALTER TABLE feed ADD COLUMN keyword_block TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE feed DROP COLUMN include_replies;
+ALTER TABLE feed DROP COLUMN include_quotes;
+ALTER TABLE feed DROP COLUMN include_reposts;
+ALTER TABLE feed DROP COLUMN media_only;
+ALTER TABLE feed DROP COLUMN keyword_allow;
+ALTER TABLE feed DROP COLUMN keyword_block;
+-- +goose StatementEnd
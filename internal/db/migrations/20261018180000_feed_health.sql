@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE feed ADD COLUMN last_error TEXT NOT NULL DEFAULT '';
+ALTER TABLE feed ADD COLUMN consecutive_failures INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE feed DROP COLUMN last_error;
+ALTER TABLE feed DROP COLUMN consecutive_failures;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE feed_post (
+    id SERIAL PRIMARY KEY,
+    feed_id INT NOT NULL REFERENCES feed ON DELETE CASCADE,
+    post_uri VARCHAR(1024) NOT NULL,
+    post_cid VARCHAR(255) NOT NULL DEFAULT '',
+    channel_id VARCHAR(255) NOT NULL,
+    message_id VARCHAR(255) NOT NULL,
+    published_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_feed_post UNIQUE (feed_id, post_uri)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE feed_post;
+-- +goose StatementEnd
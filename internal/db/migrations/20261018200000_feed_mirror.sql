@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE feed ADD COLUMN mirror VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE feed_post ADD COLUMN removed BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE feed DROP COLUMN mirror;
+ALTER TABLE feed_post DROP COLUMN removed;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE message ADD COLUMN guild_allow TEXT NOT NULL DEFAULT '';
+ALTER TABLE message ADD COLUMN guild_block TEXT NOT NULL DEFAULT '';
+ALTER TABLE message ADD COLUMN channel_allow TEXT NOT NULL DEFAULT '';
+ALTER TABLE message ADD COLUMN channel_block TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE message DROP COLUMN guild_allow;
+ALTER TABLE message DROP COLUMN guild_block;
+ALTER TABLE message DROP COLUMN channel_allow;
+ALTER TABLE message DROP COLUMN channel_block;
+-- +goose StatementEnd
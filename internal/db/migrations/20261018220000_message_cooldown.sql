@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE message ADD COLUMN channel_cooldown INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE message ADD COLUMN user_cooldown INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE message ADD COLUMN rate_limit INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE message DROP COLUMN channel_cooldown;
+ALTER TABLE message DROP COLUMN user_cooldown;
+ALTER TABLE message DROP COLUMN rate_limit;
+-- +goose StatementEnd
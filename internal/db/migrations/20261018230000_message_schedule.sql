@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE message ADD COLUMN weight INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE message ADD COLUMN active_from TIMESTAMP;
+ALTER TABLE message ADD COLUMN active_until TIMESTAMP;
+ALTER TABLE message ADD COLUMN active_days VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE message ADD COLUMN active_hour_start INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE message ADD COLUMN active_hour_end INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE message ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE message DROP COLUMN weight;
+ALTER TABLE message DROP COLUMN active_from;
+ALTER TABLE message DROP COLUMN active_until;
+ALTER TABLE message DROP COLUMN active_days;
+ALTER TABLE message DROP COLUMN active_hour_start;
+ALTER TABLE message DROP COLUMN active_hour_end;
+ALTER TABLE message DROP COLUMN timezone;
+-- +goose StatementEnd
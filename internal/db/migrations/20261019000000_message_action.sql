@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE message ADD COLUMN action VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE message ADD COLUMN embed_title TEXT NOT NULL DEFAULT '';
+ALTER TABLE message ADD COLUMN embed_color INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE message ADD COLUMN embed_image TEXT NOT NULL DEFAULT '';
+ALTER TABLE message ADD COLUMN thread_name VARCHAR(100) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE message DROP COLUMN action;
+ALTER TABLE message DROP COLUMN embed_title;
+ALTER TABLE message DROP COLUMN embed_color;
+ALTER TABLE message DROP COLUMN embed_image;
+ALTER TABLE message DROP COLUMN thread_name;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE message ADD COLUMN target_users TEXT NOT NULL DEFAULT '';
+ALTER TABLE message ADD COLUMN target_roles TEXT NOT NULL DEFAULT '';
+ALTER TABLE message ADD COLUMN role_match VARCHAR(8) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE message DROP COLUMN target_users;
+ALTER TABLE message DROP COLUMN target_roles;
+ALTER TABLE message DROP COLUMN role_match;
+-- +goose StatementEnd
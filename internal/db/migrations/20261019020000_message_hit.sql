@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE message ADD COLUMN hit_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE message ADD COLUMN last_fired_at TIMESTAMP;
+ALTER TABLE message ADD COLUMN last_channel_id VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE message ADD COLUMN last_user_id VARCHAR(32) NOT NULL DEFAULT '';
+
+CREATE TABLE message_hit (
+    message_id INTEGER NOT NULL REFERENCES message(id) ON DELETE CASCADE,
+    day TIMESTAMP NOT NULL,
+    hits INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (message_id, day)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE message_hit;
+
+ALTER TABLE message DROP COLUMN hit_count;
+ALTER TABLE message DROP COLUMN last_fired_at;
+ALTER TABLE message DROP COLUMN last_channel_id;
+ALTER TABLE message DROP COLUMN last_user_id;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE feed_post ADD COLUMN claimed_at TIMESTAMP NOT NULL DEFAULT NOW();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE feed_post DROP COLUMN claimed_at;
+-- +goose StatementEnd
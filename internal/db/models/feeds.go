@@ -3,24 +3,121 @@ package models
 import (
 	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 
 	"github.com/taiidani/no-time-to-explain/internal/bluesky"
+	"github.com/taiidani/no-time-to-explain/internal/mastodon"
+	"github.com/taiidani/no-time-to-explain/internal/rss"
 )
 
-func (q *Queries) ValidateFeed(f Feed) error {
+// Supported values for `Feed.Source`.
+const (
+	SourceBluesky  = "bluesky"
+	SourceRSS      = "rss"
+	SourceMastodon = "mastodon"
+)
+
+// Supported values for `Feed.Mirror`, controlling what happens to the Discord
+// message of a relayed post that is later deleted. Edited posts are mirrored
+// whenever the mode is not MirrorNone.
+const (
+	MirrorNone   = ""
+	MirrorDelete = "delete"
+	MirrorStrike = "strike"
+)
+
+// ValidateFeed verifies the feed's inputs, resolving the Author into the
+// source's identifier for it as the AuthorSourceID.
+func (q *Queries) ValidateFeed(f *Feed) error {
 	var ret error
 
-	client := bluesky.NewBlueskyClient()
-	user, err := client.GetUser(f.Author)
-	if err != nil {
-		ret = errors.Join(fmt.Errorf("could not look up user %q: %w", f.Author, err))
-	} else {
-		f.AuthorSourceID = user.DID
+	switch f.Source {
+	case SourceBluesky:
+		client := bluesky.NewBlueskyClient()
+		user, err := client.GetUser(f.Author)
+		if err != nil {
+			ret = errors.Join(ret, fmt.Errorf("could not look up user %q: %w", f.Author, err))
+		} else {
+			f.AuthorSourceID = user.DID
+		}
+	case SourceRSS:
+		client := rss.NewClient()
+		if _, err := client.GetFeed(f.Author); err != nil {
+			ret = errors.Join(ret, fmt.Errorf("could not load feed %q: %w", f.Author, err))
+		} else {
+			f.AuthorSourceID = f.Author
+		}
+	case SourceMastodon:
+		client := mastodon.NewClient()
+		account, err := client.LookupAccount(f.Author)
+		if err != nil {
+			ret = errors.Join(ret, fmt.Errorf("could not look up account %q: %w", f.Author, err))
+		} else {
+			f.AuthorSourceID = account.SourceID()
+		}
+	default:
+		ret = errors.Join(ret, fmt.Errorf("unknown feed source %q", f.Source))
+	}
+
+	switch f.Mirror {
+	case MirrorNone:
+	case MirrorDelete, MirrorStrike:
+		if f.Source != SourceBluesky {
+			ret = errors.Join(ret, fmt.Errorf("deletions can only be mirrored for bluesky feeds"))
+		}
+	default:
+		ret = errors.Join(ret, fmt.Errorf("unknown mirror mode %q", f.Mirror))
 	}
 
+	ret = errors.Join(ret, validatePatterns("keyword allowlist", f.KeywordAllowPatterns()))
+	ret = errors.Join(ret, validatePatterns("keyword blocklist", f.KeywordBlockPatterns()))
+
 	return ret
 }
 
 func (f *Feed) URL() string {
-	return fmt.Sprintf("https://bsky.app/profile/%s", f.AuthorSourceID)
+	switch f.Source {
+	case SourceRSS:
+		return f.AuthorSourceID
+	case SourceMastodon:
+		if username, instance, err := mastodon.ParseHandle(f.Author); err == nil {
+			return fmt.Sprintf("https://%s/@%s", instance, username)
+		}
+		return ""
+	default:
+		return fmt.Sprintf("https://bsky.app/profile/%s", f.AuthorSourceID)
+	}
+}
+
+// KeywordAllowPatterns returns the regular expressions that a post's text must
+// match at least one of in order to be relayed. One pattern is stored per line.
+func (f *Feed) KeywordAllowPatterns() []string {
+	return splitPatterns(f.KeywordAllow)
+}
+
+// KeywordBlockPatterns returns the regular expressions that prevent a post from
+// being relayed if its text matches any of them. One pattern is stored per line.
+func (f *Feed) KeywordBlockPatterns() []string {
+	return splitPatterns(f.KeywordBlock)
+}
+
+func splitPatterns(patterns string) []string {
+	ret := []string{}
+	for _, line := range strings.Split(patterns, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			ret = append(ret, line)
+		}
+	}
+	return ret
+}
+
+func validatePatterns(name string, patterns []string) error {
+	var ret error
+	for _, pattern := range patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			ret = errors.Join(ret, fmt.Errorf("invalid %s pattern %q: %w", name, pattern, err))
+		}
+	}
+	return ret
 }
@@ -3,7 +3,48 @@ package models
 import (
 	"errors"
 	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/taiidani/no-time-to-explain/internal/response"
+)
+
+// Supported values for `Message.Action`, controlling what the bot does when the
+// trigger matches. The Response is used differently by each action.
+const (
+	// ActionText sends the Response as a message.
+	ActionText = ""
+
+	// ActionReaction reacts to the triggering message with the Response, which
+	// is a single emoji.
+	ActionReaction = "reaction"
+
+	// ActionEmbed sends an embed using the Response as its description.
+	ActionEmbed = "embed"
+
+	// ActionSticker sends the sticker whose ID is the Response.
+	ActionSticker = "sticker"
+
+	// ActionThread starts a thread from the triggering message and sends the
+	// Response into it.
+	ActionThread = "thread"
+
+	// ActionDelete deletes the triggering message, then sends the Response as
+	// a notice if it is not empty.
+	ActionDelete = "delete"
+)
+
+// Supported values for `Message.RoleMatch`, controlling how the TargetRoles of
+// a message are matched against the roles of the sender.
+const (
+	// RoleMatchAny targets senders with at least one of the roles.
+	RoleMatchAny = ""
+
+	// RoleMatchAll targets senders with every one of the roles.
+	RoleMatchAll = "all"
 )
 
 func (q *Queries) ValidateMessage(m Message) error {
@@ -12,8 +53,282 @@ func (q *Queries) ValidateMessage(m Message) error {
 	m.Sender = strings.TrimPrefix(m.Sender, "@")
 	m.Response = strings.TrimSpace(m.Response)
 
-	if len(m.Trigger) < 4 || len(m.Response) < 4 {
+	if len(m.Trigger) < 4 {
 		ret = errors.Join(ret, fmt.Errorf("provided inputs need to be at least 4 characters"))
 	}
+
+	switch m.Action {
+	case ActionText, ActionEmbed, ActionThread:
+		if len(m.Response) < 4 {
+			ret = errors.Join(ret, fmt.Errorf("provided inputs need to be at least 4 characters"))
+		}
+	case ActionReaction:
+		if m.Response == "" || strings.ContainsAny(m.Response, " \t\n") {
+			ret = errors.Join(ret, fmt.Errorf("reactions must be a single emoji"))
+		}
+	case ActionSticker:
+		ret = errors.Join(ret, validateIDs("sticker", []string{m.Response}))
+	case ActionDelete:
+	default:
+		ret = errors.Join(ret, fmt.Errorf("unknown response action %q", m.Action))
+	}
+
+	if m.EmbedColor < 0 || m.EmbedColor > 0xFFFFFF {
+		ret = errors.Join(ret, fmt.Errorf("invalid embed color %d", m.EmbedColor))
+	}
+	if m.EmbedImage != "" && !strings.HasPrefix(m.EmbedImage, "https://") && !strings.HasPrefix(m.EmbedImage, "http://") {
+		ret = errors.Join(ret, fmt.Errorf("embed image must be a URL"))
+	}
+	if len(m.ThreadName) > 100 {
+		ret = errors.Join(ret, fmt.Errorf("thread names cannot be longer than 100 characters"))
+	}
+
+	trigger, err := regexp.Compile(m.Trigger)
+	if err != nil {
+		ret = errors.Join(ret, fmt.Errorf("invalid trigger %q: %w", m.Trigger, err))
+	}
+
+	tpl, err := response.Parse(m.Response)
+	if err != nil {
+		ret = errors.Join(ret, fmt.Errorf("invalid response: %w", err))
+	} else if trigger != nil {
+		ret = errors.Join(ret, tpl.Validate(trigger))
+	}
+
+	ret = errors.Join(ret,
+		validateIDs("guild allowlist", m.GuildAllowIDs()),
+		validateIDs("guild blocklist", m.GuildBlockIDs()),
+		validateIDs("channel allowlist", m.ChannelAllowIDs()),
+		validateIDs("channel blocklist", m.ChannelBlockIDs()),
+		validateIDs("target user", m.TargetUserIDs()),
+		validateIDs("target role", m.TargetRoleIDs()),
+	)
+
+	switch m.RoleMatch {
+	case RoleMatchAny, RoleMatchAll:
+	default:
+		ret = errors.Join(ret, fmt.Errorf("unknown role match %q", m.RoleMatch))
+	}
+
+	if m.ChannelCooldown < 0 || m.UserCooldown < 0 || m.RateLimit < 0 {
+		ret = errors.Join(ret, fmt.Errorf("cooldowns and rate limits cannot be negative"))
+	}
+
+	if m.Weight < 1 {
+		ret = errors.Join(ret, fmt.Errorf("weight must be at least 1"))
+	}
+	if m.ActiveFrom.Valid && m.ActiveUntil.Valid && m.ActiveUntil.Time.Before(m.ActiveFrom.Time) {
+		ret = errors.Join(ret, fmt.Errorf("active dates end before they start"))
+	}
+	for _, day := range m.ActiveDayList() {
+		if n, err := strconv.Atoi(day); err != nil || n < 0 || n > 6 {
+			ret = errors.Join(ret, fmt.Errorf("invalid active day %q", day))
+		}
+	}
+	if m.ActiveHourStart < 0 || m.ActiveHourStart > 23 || m.ActiveHourEnd < 0 || m.ActiveHourEnd > 23 {
+		ret = errors.Join(ret, fmt.Errorf("active hours must be between 0 and 23"))
+	}
+	if _, err := m.Location(); err != nil {
+		ret = errors.Join(ret, fmt.Errorf("invalid timezone %q: %w", m.Timezone, err))
+	}
+	return ret
+}
+
+// ActiveDayList returns the days of the week that the message is active on,
+// numbered from Sunday as 0. An empty list means every day.
+func (m Message) ActiveDayList() []string {
+	return splitIDs(m.ActiveDays)
+}
+
+// Scheduled reports whether the message is only active at certain times.
+func (m Message) Scheduled() bool {
+	return m.ActiveFrom.Valid || m.ActiveUntil.Valid || m.ActiveDays != "" || m.ActiveHourStart != m.ActiveHourEnd
+}
+
+// Location returns the timezone that the message's schedule is evaluated in,
+// which is UTC unless configured.
+func (m Message) Location() (*time.Location, error) {
+	if m.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(m.Timezone)
+}
+
+// ActiveAt reports whether the message's schedule allows it to respond at the
+// given time, as seen in the given timezone. The active dates are inclusive,
+// and the active hours run from the start of the first to the start of the
+// last, wrapping past midnight if the last is earlier.
+func (m Message) ActiveAt(t time.Time, loc *time.Location) bool {
+	t = t.In(loc)
+
+	date := t.Format(time.DateOnly)
+	if m.ActiveFrom.Valid && date < m.ActiveFrom.Time.Format(time.DateOnly) {
+		return false
+	} else if m.ActiveUntil.Valid && date > m.ActiveUntil.Time.Format(time.DateOnly) {
+		return false
+	}
+
+	if days := m.ActiveDayList(); len(days) > 0 && !slices.Contains(days, strconv.Itoa(int(t.Weekday()))) {
+		return false
+	}
+
+	start, end, hour := int(m.ActiveHourStart), int(m.ActiveHourEnd), t.Hour()
+	switch {
+	case start < end:
+		return hour >= start && hour < end
+	case start > end:
+		return hour >= start || hour < end
+	}
+
+	return true
+}
+
+// ActiveNow reports whether the message may respond at the current time.
+func (m Message) ActiveNow() bool {
+	loc, err := m.Location()
+	if err != nil {
+		loc = time.UTC
+	}
+	return m.ActiveAt(time.Now(), loc)
+}
+
+// ChannelCooldownDuration returns how long the message waits before responding
+// again in the same channel. Zero disables the cooldown.
+func (m Message) ChannelCooldownDuration() time.Duration {
+	return time.Duration(m.ChannelCooldown) * time.Second
+}
+
+// UserCooldownDuration returns how long the message waits before responding
+// to the same user again. Zero disables the cooldown.
+func (m Message) UserCooldownDuration() time.Duration {
+	return time.Duration(m.UserCooldown) * time.Second
+}
+
+// Throttled reports whether the message has any cooldown or rate limit.
+func (m Message) Throttled() bool {
+	return m.ChannelCooldown > 0 || m.UserCooldown > 0 || m.RateLimit > 0
+}
+
+// GuildAllowIDs returns the guilds that the message is limited to. An empty
+// list allows every guild. IDs are stored comma separated.
+func (m Message) GuildAllowIDs() []string {
+	return splitIDs(m.GuildAllow)
+}
+
+// GuildBlockIDs returns the guilds that the message never responds in.
+func (m Message) GuildBlockIDs() []string {
+	return splitIDs(m.GuildBlock)
+}
+
+// ChannelAllowIDs returns the channels that the message is limited to. An empty
+// list allows every channel.
+func (m Message) ChannelAllowIDs() []string {
+	return splitIDs(m.ChannelAllow)
+}
+
+// ChannelBlockIDs returns the channels that the message never responds in.
+func (m Message) ChannelBlockIDs() []string {
+	return splitIDs(m.ChannelBlock)
+}
+
+// Scoped reports whether the message is limited to or blocked from any guilds
+// or channels.
+func (m Message) Scoped() bool {
+	return m.GuildAllow != "" || m.GuildBlock != "" || m.ChannelAllow != "" || m.ChannelBlock != ""
+}
+
+// InScope reports whether the message may respond in the given guild and
+// channel. Direct messages have no guild, so never match a guild allowlist.
+func (m Message) InScope(guildID, channelID string) bool {
+	if allow := m.GuildAllowIDs(); len(allow) > 0 && !slices.Contains(allow, guildID) {
+		return false
+	} else if slices.Contains(m.GuildBlockIDs(), guildID) {
+		return false
+	}
+
+	if allow := m.ChannelAllowIDs(); len(allow) > 0 && !slices.Contains(allow, channelID) {
+		return false
+	} else if slices.Contains(m.ChannelBlockIDs(), channelID) {
+		return false
+	}
+
+	return true
+}
+
+// TargetUserIDs returns the Discord users that the message responds to.
+func (m Message) TargetUserIDs() []string {
+	return splitIDs(m.TargetUsers)
+}
+
+// TargetRoleIDs returns the Discord roles that the message responds to.
+func (m Message) TargetRoleIDs() []string {
+	return splitIDs(m.TargetRoles)
+}
+
+// Targeted reports whether the message only responds to certain users or roles.
+func (m Message) Targeted() bool {
+	return m.TargetUsers != "" || m.TargetRoles != ""
+}
+
+// TargetsMember reports whether the message responds to the sender with the
+// given user ID and roles. A sender is targeted if they are one of the target
+// users or have the target roles, so that a message may respond to particular
+// people as well as to everyone with a role. Messages without targets respond
+// to everyone.
+func (m Message) TargetsMember(userID string, roles []string) bool {
+	if !m.Targeted() {
+		return true
+	}
+
+	if userID != "" && slices.Contains(m.TargetUserIDs(), userID) {
+		return true
+	}
+
+	targetRoles := m.TargetRoleIDs()
+	if len(targetRoles) == 0 {
+		return false
+	}
+
+	for _, role := range targetRoles {
+		has := slices.Contains(roles, role)
+		if has && m.RoleMatch != RoleMatchAll {
+			return true
+		} else if !has && m.RoleMatch == RoleMatchAll {
+			return false
+		}
+	}
+
+	return m.RoleMatch == RoleMatchAll
+}
+
+// JoinIDs formats a list of IDs for storage, such as guilds, channels or days.
+func JoinIDs(ids []string) string {
+	ret := []string{}
+	for _, id := range ids {
+		if id = strings.TrimSpace(id); id != "" && !slices.Contains(ret, id) {
+			ret = append(ret, id)
+		}
+	}
+	return strings.Join(ret, ",")
+}
+
+func splitIDs(ids string) []string {
+	ret := []string{}
+	for _, id := range strings.Split(ids, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ret = append(ret, id)
+		}
+	}
+	return ret
+}
+
+// validateIDs verifies that each ID is a Discord snowflake.
+func validateIDs(name string, ids []string) error {
+	var ret error
+	for _, id := range ids {
+		if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+			ret = errors.Join(ret, fmt.Errorf("invalid %s ID %q", name, id))
+		}
+	}
 	return ret
 }
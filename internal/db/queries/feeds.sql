@@ -1,22 +1,90 @@
+-- name: GetFeed :one
+SELECT *
+FROM feed
+WHERE id = $1 LIMIT 1;
+
 -- name: LoadFeeds :many
 SELECT *
 FROM feed
 ORDER BY source, author;
 
 -- name: CreateFeed :one
-INSERT INTO feed (source, author, author_source_id, last_message)
-VALUES ($1, $2, $3, $4)
+INSERT INTO feed (source, author, author_source_id, last_message, channel_id, rich_embed)
+VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING *;
 
--- name: UpdateFeed :one
+-- name: UpdateFeedSettings :one
 UPDATE feed SET
   source = $2,
   author = $3,
   author_source_id = $4,
-  last_message = $5
+  channel_id = $5,
+  rich_embed = $6,
+  include_replies = $7,
+  include_quotes = $8,
+  include_reposts = $9,
+  media_only = $10,
+  keyword_allow = $11,
+  keyword_block = $12,
+  mirror = $13
 WHERE id = $1
 RETURNING *;
 
+-- name: UpdateFeedLastMessage :exec
+UPDATE feed SET
+  last_message = $2
+WHERE id = $1;
+
+-- name: RecordFeedFailure :exec
+UPDATE feed SET
+  last_error = $2,
+  consecutive_failures = consecutive_failures + 1
+WHERE id = $1;
+
+-- name: ResetFeedFailures :exec
+UPDATE feed SET
+  last_error = '',
+  consecutive_failures = 0
+WHERE id = $1 AND consecutive_failures > 0;
+
 -- name: DeleteFeed :exec
 DELETE FROM feed
 WHERE id = $1;
+
+-- name: ClaimFeedPost :execrows
+INSERT INTO feed_post (feed_id, post_uri, post_cid, channel_id, message_id, published_at)
+VALUES ($1, $2, $3, $4, '', $5)
+ON CONFLICT (feed_id, post_uri) DO UPDATE SET
+  post_cid = EXCLUDED.post_cid,
+  channel_id = EXCLUDED.channel_id,
+  claimed_at = NOW()
+WHERE feed_post.message_id = '' AND feed_post.claimed_at < $6;
+
+-- name: UpdateFeedPostMessage :exec
+UPDATE feed_post SET
+  message_id = $3
+WHERE feed_id = $1 AND post_uri = $2;
+
+-- name: ReleaseFeedPost :exec
+DELETE FROM feed_post
+WHERE feed_id = $1 AND post_uri = $2 AND message_id = '';
+
+-- name: LoadRecentFeedPosts :many
+SELECT *
+FROM feed_post
+WHERE feed_id = $1 AND published_at > $2 AND removed = FALSE AND message_id <> ''
+ORDER BY published_at;
+
+-- name: UpdateFeedPostCid :exec
+UPDATE feed_post SET
+  post_cid = $2
+WHERE id = $1;
+
+-- name: MarkFeedPostRemoved :exec
+UPDATE feed_post SET
+  removed = TRUE
+WHERE id = $1;
+
+-- name: PruneFeedPosts :execrows
+DELETE FROM feed_post
+WHERE created_at < $1;
@@ -9,8 +9,8 @@ FROM message
 ORDER BY trigger, response;
 
 -- name: CreateMessage :one
-INSERT INTO message (enabled, sender, trigger, response)
-VALUES ($1, $2, $3, $4)
+INSERT INTO message (enabled, sender, trigger, response, guild_allow, guild_block, channel_allow, channel_block, channel_cooldown, user_cooldown, rate_limit, weight, active_from, active_until, active_days, active_hour_start, active_hour_end, timezone, action, embed_title, embed_color, embed_image, thread_name, target_users, target_roles, role_match)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26)
 RETURNING *;
 
 -- name: UpdateMessage :one
@@ -18,10 +18,52 @@ UPDATE message SET
     enabled = $2,
     sender = $3,
     trigger = $4,
-    response = $5
+    response = $5,
+    guild_allow = $6,
+    guild_block = $7,
+    channel_allow = $8,
+    channel_block = $9,
+    channel_cooldown = $10,
+    user_cooldown = $11,
+    rate_limit = $12,
+    weight = $13,
+    active_from = $14,
+    active_until = $15,
+    active_days = $16,
+    active_hour_start = $17,
+    active_hour_end = $18,
+    timezone = $19,
+    action = $20,
+    embed_title = $21,
+    embed_color = $22,
+    embed_image = $23,
+    thread_name = $24,
+    target_users = $25,
+    target_roles = $26,
+    role_match = $27
 WHERE id = $1
 RETURNING *;
 
 -- name: DeleteMessage :exec
 DELETE FROM message
 WHERE id = $1;
+
+-- name: RecordMessageHit :exec
+UPDATE message SET
+    hit_count = hit_count + 1,
+    last_fired_at = $2,
+    last_channel_id = $3,
+    last_user_id = $4
+WHERE id = $1;
+
+-- name: RecordMessageHitDay :exec
+INSERT INTO message_hit (message_id, day, hits)
+VALUES ($1, $2, 1)
+ON CONFLICT (message_id, day) DO UPDATE SET
+    hits = message_hit.hits + 1;
+
+-- name: LoadMessageHits :many
+SELECT *
+FROM message_hit
+WHERE day >= $1
+ORDER BY message_id, day;
@@ -0,0 +1,197 @@
+package internal
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/taiidani/no-time-to-explain/internal/bluesky"
+	"github.com/taiidani/no-time-to-explain/internal/mastodon"
+	"github.com/taiidani/no-time-to-explain/internal/rss"
+)
+
+const (
+	// defaultColor is the accent of relayed feed items.
+	defaultColor = 0x05FF05
+
+	// blueskyColor is the Bluesky brand color, used as the accent of relayed posts.
+	blueskyColor = 0x1185FE
+
+	// mastodonColor is the Mastodon brand color, used as the accent of relayed statuses.
+	mastodonColor = 0x6364FF
+)
+
+// blueskyEmbed renders the given post as a Discord embed, so that it may be
+// relayed without relying upon Discord's link unfurling.
+func blueskyEmbed(post bluesky.FeedPost) *discordgo.MessageEmbed {
+	name := post.Author.DisplayName
+	if name == "" {
+		name = post.Author.Handle
+	}
+
+	ret := &discordgo.MessageEmbed{
+		Type: discordgo.EmbedTypeRich,
+		URL:  post.URL(),
+		Author: &discordgo.MessageEmbedAuthor{
+			Name:    fmt.Sprintf("%s (@%s)", name, post.Author.Handle),
+			URL:     post.Author.URL(),
+			IconURL: post.Author.Avatar,
+		},
+		Title:       "View on Bluesky",
+		Description: post.Record.Markdown(),
+		Color:       blueskyColor,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("❤️ %d  🔁 %d", post.LikeCount, post.RepostCount),
+		},
+		Timestamp: post.IndexedAt.Format(time.RFC3339),
+	}
+
+	if quoted := post.Embed.Quoted(); quoted != nil && quoted.Value != nil {
+		ret.Fields = append(ret.Fields, &discordgo.MessageEmbedField{
+			Name:  fmt.Sprintf("Quoting @%s", quoted.Author.Handle),
+			Value: truncate(quoted.Value.Markdown(), 1024),
+		})
+	}
+
+	// Attach the first piece of media, if there is one
+	if media := post.Embed.Attachment(); media != nil {
+		switch media.Type {
+		case bluesky.EmbedImagesView:
+			if len(media.Images) > 0 {
+				ret.Image = &discordgo.MessageEmbedImage{URL: media.Images[0].Fullsize}
+			}
+		case bluesky.EmbedVideoView:
+			if media.Thumbnail != "" {
+				ret.Image = &discordgo.MessageEmbedImage{URL: media.Thumbnail}
+			}
+		case bluesky.EmbedExternalView:
+			if media.External != nil {
+				title := media.External.Title
+				if title == "" {
+					title = "Link"
+				}
+				ret.Fields = append(ret.Fields, &discordgo.MessageEmbedField{
+					Name:  truncate(title, 256),
+					Value: truncate(media.External.Description, 900) + "\n" + media.External.URI,
+				})
+				if media.External.Thumb != "" {
+					ret.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: media.External.Thumb}
+				}
+			}
+		}
+	}
+
+	return ret
+}
+
+// rssEmbed renders the given feed item as a Discord embed.
+func rssEmbed(feed *rss.Feed, item rss.Item) *discordgo.MessageEmbed {
+	ret := &discordgo.MessageEmbed{
+		Type:        discordgo.EmbedTypeRich,
+		URL:         item.Link,
+		Title:       truncate(item.Title, 256),
+		Description: truncate(stripHTML(item.Summary), 4096),
+		Color:       defaultColor,
+	}
+
+	if feed.Title != "" {
+		ret.Author = &discordgo.MessageEmbedAuthor{
+			Name: truncate(feed.Title, 256),
+			URL:  feed.Link,
+		}
+	}
+
+	if !item.Published.IsZero() {
+		ret.Timestamp = item.Published.Format(time.RFC3339)
+	}
+
+	return ret
+}
+
+// mastodonEmbed renders the given status as a Discord embed.
+func mastodonEmbed(status mastodon.Status) *discordgo.MessageEmbed {
+	name := status.Account.DisplayName
+	if name == "" {
+		name = status.Account.Username
+	}
+
+	description := stripHTML(status.Content)
+	if status.SpoilerText != "" {
+		description = fmt.Sprintf("**CW: %s**\n||%s||", status.SpoilerText, description)
+	}
+
+	ret := &discordgo.MessageEmbed{
+		Type: discordgo.EmbedTypeRich,
+		URL:  status.URL,
+		Author: &discordgo.MessageEmbedAuthor{
+			Name:    truncate(fmt.Sprintf("%s (@%s)", name, status.Account.Acct), 256),
+			URL:     status.Account.URL,
+			IconURL: status.Account.Avatar,
+		},
+		Title:       "View on Mastodon",
+		Description: truncate(description, 4096),
+		Color:       mastodonColor,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("⭐ %d  🔁 %d", status.FavouritesCount, status.ReblogsCount),
+		},
+		Timestamp: status.CreatedAt.Format(time.RFC3339),
+	}
+
+	// Attach the first piece of media, falling back upon the link card
+	for _, media := range status.MediaAttachments {
+		if media.Type == "image" || media.Type == "gifv" || media.Type == "video" {
+			ret.Image = &discordgo.MessageEmbedImage{URL: media.PreviewURL}
+			break
+		}
+	}
+	if ret.Image == nil && status.Card != nil {
+		title := status.Card.Title
+		if title == "" {
+			title = "Link"
+		}
+		ret.Fields = append(ret.Fields, &discordgo.MessageEmbedField{
+			Name:  truncate(title, 256),
+			Value: truncate(status.Card.Description, 900) + "\n" + status.Card.URL,
+		})
+		if status.Card.Image != "" {
+			ret.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: status.Card.Image}
+		}
+	}
+
+	return ret
+}
+
+var (
+	// htmlBreakPattern matches the HTML elements that separate lines of text.
+	htmlBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</p>\s*<p[^>]*>`)
+
+	// htmlTagPattern matches the tags within an HTML fragment.
+	htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+)
+
+// stripHTML converts the given HTML fragment, such as an RSS description, into
+// plain text.
+func stripHTML(text string) string {
+	text = htmlBreakPattern.ReplaceAllString(text, "\n")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	return strings.TrimSpace(html.UnescapeString(text))
+}
+
+// truncate shortens the given text to at most limit bytes, in order to fit
+// within Discord's embed field limits.
+func truncate(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+
+	// Avoid cutting a multi-byte character in half
+	cut := limit - len("…")
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "…"
+}
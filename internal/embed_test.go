@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/taiidani/no-time-to-explain/internal/bluesky"
+)
+
+func Test_blueskyEmbed(t *testing.T) {
+	tm := time.Date(2025, time.May, 1, 12, 0, 0, 0, time.UTC)
+	post := bluesky.FeedPost{
+		URI: "at://did:plc:abc/app.bsky.feed.post/3lnape7mfxs27",
+		Author: bluesky.FeedPostAuthor{
+			Handle:      "destinythegame.bungie.net",
+			DisplayName: "Destiny 2",
+			Avatar:      "https://cdn/avatar.jpg",
+		},
+		IndexedAt:   tm,
+		LikeCount:   10,
+		RepostCount: 2,
+		Record:      bluesky.FeedPostRecord{Text: "New patch notes"},
+	}
+
+	t.Run("images", func(t *testing.T) {
+		post := post
+		post.Embed = &bluesky.PostEmbedView{
+			Type: bluesky.EmbedImagesView,
+			Images: []bluesky.EmbedImageView{
+				{Fullsize: "https://cdn/first.jpg"},
+				{Fullsize: "https://cdn/second.jpg"},
+			},
+		}
+
+		got := blueskyEmbed(post)
+		if got.URL != "https://bsky.app/profile/destinythegame.bungie.net/post/3lnape7mfxs27" {
+			t.Errorf("URL = %q", got.URL)
+		}
+		if got.Author.Name != "Destiny 2 (@destinythegame.bungie.net)" || got.Author.IconURL != "https://cdn/avatar.jpg" {
+			t.Errorf("Author = %+v", got.Author)
+		}
+		if got.Description != "New patch notes" {
+			t.Errorf("Description = %q", got.Description)
+		}
+		if got.Image == nil || got.Image.URL != "https://cdn/first.jpg" {
+			t.Errorf("Image = %+v, want first image", got.Image)
+		}
+		if got.Footer.Text != "❤️ 10  🔁 2" {
+			t.Errorf("Footer = %q", got.Footer.Text)
+		}
+		if got.Timestamp != "2025-05-01T12:00:00Z" {
+			t.Errorf("Timestamp = %q", got.Timestamp)
+		}
+	})
+
+	t.Run("external", func(t *testing.T) {
+		post := post
+		post.Embed = &bluesky.PostEmbedView{
+			Type: bluesky.EmbedExternalView,
+			External: &bluesky.EmbedExternalViewData{
+				URI:         "https://bungie.net/7020",
+				Title:       "Update 9.0.0",
+				Description: strings.Repeat("a", 2000),
+				Thumb:       "https://cdn/thumb.jpg",
+			},
+		}
+
+		got := blueskyEmbed(post)
+		if len(got.Fields) != 1 || got.Fields[0].Name != "Update 9.0.0" {
+			t.Fatalf("Fields = %+v, want link card", got.Fields)
+		}
+		if len(got.Fields[0].Value) > 1024 || !strings.HasSuffix(got.Fields[0].Value, "https://bungie.net/7020") {
+			t.Errorf("Field value = %q", got.Fields[0].Value)
+		}
+		if got.Thumbnail == nil || got.Thumbnail.URL != "https://cdn/thumb.jpg" {
+			t.Errorf("Thumbnail = %+v", got.Thumbnail)
+		}
+	})
+
+	t.Run("no embed", func(t *testing.T) {
+		got := blueskyEmbed(post)
+		if got.Image != nil || got.Thumbnail != nil || len(got.Fields) != 0 {
+			t.Errorf("blueskyEmbed() = %+v, want no media", got)
+		}
+	})
+}
+
+func Test_truncate(t *testing.T) {
+	if got := truncate("short", 10); got != "short" {
+		t.Errorf("truncate() = %q, want unchanged", got)
+	}
+	if got := truncate("🔧🔧🔧", 9); got != "🔧…" {
+		t.Errorf("truncate() = %q, want a whole character", got)
+	}
+}
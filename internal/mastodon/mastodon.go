@@ -0,0 +1,182 @@
+package mastodon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Client struct {
+	// Scheme is used to reach each instance. It is only overridden in tests.
+	Scheme     string
+	HttpClient *http.Client
+}
+
+// NewClient creates a new client for the public Mastodon REST API
+func NewClient() *Client {
+	return &Client{
+		Scheme:     "https",
+		HttpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// APIError is returned when the API responds with an unsuccessful status.
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Body)
+}
+
+// Account contains basic identifying information about the given user.
+type Account struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	Acct        string `json:"acct"`
+	DisplayName string `json:"display_name"`
+	URL         string `json:"url"`
+	Avatar      string `json:"avatar"`
+
+	// Instance is the host the account was looked up on.
+	Instance string `json:"-"`
+}
+
+// SourceID returns the identifier used to fetch the account's statuses with
+// `GetStatuses`. It includes the instance since account IDs are only unique
+// within an instance.
+func (a *Account) SourceID() string {
+	return a.Instance + "/" + a.ID
+}
+
+// ParseHandle splits a handle such as "@user@mastodon.social" into its username
+// and instance.
+func ParseHandle(handle string) (username string, instance string, err error) {
+	handle = strings.TrimPrefix(strings.TrimSpace(handle), "@")
+	username, instance, found := strings.Cut(handle, "@")
+	if !found || username == "" || instance == "" {
+		return "", "", fmt.Errorf("handle %q must be in the form user@instance", handle)
+	}
+
+	return username, instance, nil
+}
+
+// LookupAccount fetches account data by handle, such as "user@mastodon.social"
+func (c *Client) LookupAccount(handle string) (*Account, error) {
+	username, instance, err := ParseHandle(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Add("acct", username)
+	url := fmt.Sprintf("%s://%s/api/v1/accounts/lookup?%s", c.Scheme, instance, params.Encode())
+
+	var ret Account
+	if err := c.get(url, &ret); err != nil {
+		return nil, err
+	}
+	ret.Instance = instance
+
+	return &ret, nil
+}
+
+// Status is a single post in an account's timeline.
+type Status struct {
+	ID               string            `json:"id"`
+	URI              string            `json:"uri"`
+	URL              string            `json:"url"`
+	CreatedAt        time.Time         `json:"created_at"`
+	Account          Account           `json:"account"`
+	Content          string            `json:"content"`
+	SpoilerText      string            `json:"spoiler_text"`
+	Visibility       string            `json:"visibility"`
+	InReplyToID      *string           `json:"in_reply_to_id"`
+	Reblog           *Status           `json:"reblog"`
+	Quote            *Quote            `json:"quote"`
+	MediaAttachments []MediaAttachment `json:"media_attachments"`
+	Card             *Card             `json:"card"`
+	FavouritesCount  int               `json:"favourites_count"`
+	ReblogsCount     int               `json:"reblogs_count"`
+}
+
+// Quote is the status quoted by a `Status`, as reported by servers that support
+// quote posts.
+type Quote struct {
+	State        string  `json:"state"`
+	QuotedStatus *Status `json:"quoted_status"`
+}
+
+// MediaAttachment is an image, video or audio file attached to a `Status`.
+type MediaAttachment struct {
+	Type        string `json:"type"`
+	URL         string `json:"url"`
+	PreviewURL  string `json:"preview_url"`
+	Description string `json:"description"`
+}
+
+// Card is the preview generated for the first link in a `Status`.
+type Card struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Image       string `json:"image"`
+}
+
+// StatusOptions controls which page of an account's statuses is returned by
+// `GetStatuses`.
+type StatusOptions struct {
+	// MaxID returns only statuses older than the given status ID. Leave empty to
+	// fetch the most recent page.
+	MaxID string
+
+	// Limit is the maximum number of statuses to return, up to 40. Leave as 0 to
+	// use the API default.
+	Limit int
+}
+
+// GetStatuses fetches a page of an account's public statuses, newest first. The
+// sourceID is as returned by `Account.SourceID`.
+func (c *Client) GetStatuses(sourceID string, opts StatusOptions) ([]Status, error) {
+	instance, accountID, found := strings.Cut(sourceID, "/")
+	if !found || instance == "" || accountID == "" {
+		return nil, fmt.Errorf("invalid account %q", sourceID)
+	}
+
+	params := url.Values{}
+	if opts.MaxID != "" {
+		params.Add("max_id", opts.MaxID)
+	}
+	if opts.Limit > 0 {
+		params.Add("limit", strconv.Itoa(opts.Limit))
+	}
+	url := fmt.Sprintf("%s://%s/api/v1/accounts/%s/statuses?%s", c.Scheme, instance, url.PathEscape(accountID), params.Encode())
+
+	ret := []Status{}
+	if err := c.get(url, &ret); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func (c *Client) get(url string, ret any) error {
+	resp, err := c.HttpClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+	}
+
+	return json.NewDecoder(resp.Body).Decode(ret)
+}
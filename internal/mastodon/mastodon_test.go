@@ -0,0 +1,95 @@
+package mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testClient(t *testing.T, handler http.HandlerFunc) (*Client, string) {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	c := NewClient()
+	c.Scheme = "http"
+	return c, strings.TrimPrefix(srv.URL, "http://")
+}
+
+func TestParseHandle(t *testing.T) {
+	tests := []struct {
+		handle       string
+		wantUsername string
+		wantInstance string
+		wantErr      bool
+	}{
+		{handle: "@bungie@mastodon.social", wantUsername: "bungie", wantInstance: "mastodon.social"},
+		{handle: "bungie@mastodon.social", wantUsername: "bungie", wantInstance: "mastodon.social"},
+		{handle: "bungie", wantErr: true},
+		{handle: "@mastodon.social", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.handle, func(t *testing.T) {
+			username, instance, err := ParseHandle(tt.handle)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHandle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if username != tt.wantUsername || instance != tt.wantInstance {
+				t.Errorf("ParseHandle() = %q, %q, want %q, %q", username, instance, tt.wantUsername, tt.wantInstance)
+			}
+		})
+	}
+}
+
+func TestClient_LookupAccount(t *testing.T) {
+	c, instance := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/accounts/lookup" || r.URL.Query().Get("acct") != "bungie" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": "109", "username": "bungie", "acct": "bungie", "display_name": "Bungie"}`))
+	})
+
+	got, err := c.LookupAccount("@bungie@" + instance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != "109" || got.DisplayName != "Bungie" {
+		t.Errorf("LookupAccount() = %+v", got)
+	}
+	if got.SourceID() != instance+"/109" {
+		t.Errorf("SourceID() = %q, want %q", got.SourceID(), instance+"/109")
+	}
+
+	if _, err := c.LookupAccount("@missing@" + instance); err == nil {
+		t.Error("LookupAccount() expected an error for a missing account")
+	}
+}
+
+func TestClient_GetStatuses(t *testing.T) {
+	c, instance := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/accounts/109/statuses" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("max_id") != "2" || r.URL.Query().Get("limit") != "40" {
+			t.Errorf("query = %q", r.URL.RawQuery)
+		}
+		_, _ = w.Write([]byte(`[
+			{"id": "1", "created_at": "2025-06-10T17:00:00.000Z", "content": "<p>Hello</p>", "in_reply_to_id": null, "reblog": null},
+			{"id": "0", "created_at": "2025-06-09T17:00:00.000Z", "reblog": {"id": "5", "content": "Boosted"}}
+		]`))
+	})
+
+	got, err := c.GetStatuses(instance+"/109", StatusOptions{MaxID: "2", Limit: 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Content != "<p>Hello</p>" || got[1].Reblog == nil {
+		t.Errorf("GetStatuses() = %+v", got)
+	}
+
+	if _, err := c.GetStatuses("109", StatusOptions{}); err == nil {
+		t.Error("GetStatuses() expected an error for a source ID without an instance")
+	}
+}
@@ -0,0 +1,166 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/taiidani/no-time-to-explain/internal/db/models"
+)
+
+// defaultMirrorWindow is how long after being relayed a post is checked for
+// deletions and edits, unless overridden by FEED_MIRROR_WINDOW.
+const defaultMirrorWindow = 24 * time.Hour
+
+// mirrorFeed deletes or strikes through the Discord messages of relayed posts
+// that have since been deleted, and updates the messages of posts that have
+// since been edited.
+func mirrorFeed(ctx context.Context, logger *slog.Logger, queries *models.Queries, discord *discordgo.Session, syncer feedSyncer, feed models.Feed) error {
+	window := envDuration("FEED_MIRROR_WINDOW", defaultMirrorWindow)
+
+	relayed, err := queries.LoadRecentFeedPosts(ctx, models.LoadRecentFeedPostsParams{
+		FeedID:      feed.ID,
+		PublishedAt: time.Now().Add(-window),
+	})
+	if err != nil {
+		return fmt.Errorf("could not load feed ledger: %w", err)
+	} else if len(relayed) == 0 {
+		return nil
+	}
+
+	ids := []string{}
+	for _, post := range relayed {
+		ids = append(ids, post.PostUri)
+	}
+
+	var current map[string]feedEntry
+	err = retry(ctx, logger, retryAttempts, retryBaseDelay, func() (err error) {
+		current, err = syncer.Current(ctx, logger, feed, ids)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s feed %s mirror error: %w", feed.Source, feed.Author, err)
+	}
+
+	var errs error
+	for _, post := range relayed {
+		postLogger := logger.With("post_uri", post.PostUri, "message_id", post.MessageID)
+
+		entry, found := current[post.PostUri]
+		switch {
+		case !found:
+			postLogger.Info("mirroring deleted feed entry", "mirror", feed.Mirror)
+			if err := mirrorDeletion(ctx, discord, feed.Mirror, post); err != nil {
+				errs = errors.Join(errs, fmt.Errorf("could not mirror deletion of %s: %w", post.PostUri, err))
+				continue
+			}
+
+			if err := queries.MarkFeedPostRemoved(ctx, post.ID); err != nil {
+				errs = errors.Join(errs, err)
+			}
+		case entry.CID != "" && entry.CID != post.PostCid:
+			postLogger.Info("mirroring edited feed entry", "cid", entry.CID, "previous_cid", post.PostCid)
+			_, err := discord.ChannelMessageEditComplex(mirrorEdit(feed, post, entry), discordgo.WithContext(ctx))
+			if err != nil && !isUnknownMessage(err) {
+				errs = errors.Join(errs, fmt.Errorf("could not mirror edit of %s: %w", post.PostUri, err))
+				continue
+			}
+
+			err = queries.UpdateFeedPostCid(ctx, models.UpdateFeedPostCidParams{
+				ID:      post.ID,
+				PostCid: entry.CID,
+			})
+			if err != nil {
+				errs = errors.Join(errs, err)
+			}
+		}
+	}
+
+	return errs
+}
+
+// mirrorEdit replaces the content of a relayed message with that of the edited
+// entry. The embeds are only replaced when the feed sends its own, as sending
+// no embeds would also remove the link preview of a plain relay.
+func mirrorEdit(feed models.Feed, post models.FeedPost, entry feedEntry) *discordgo.MessageEdit {
+	ret := &discordgo.MessageEdit{
+		ID:      post.MessageID,
+		Channel: post.ChannelID,
+		Content: &entry.Message.Content,
+	}
+	if feed.RichEmbed || len(entry.Message.Embeds) > 0 {
+		ret.Embeds = &entry.Message.Embeds
+	}
+	return ret
+}
+
+// mirrorDeletion removes the Discord message of a deleted post, or strikes it
+// through so that readers can see what was retracted.
+func mirrorDeletion(ctx context.Context, discord *discordgo.Session, mode string, post models.FeedPost) error {
+	if mode == models.MirrorDelete {
+		err := discord.ChannelMessageDelete(post.ChannelID, post.MessageID, discordgo.WithContext(ctx))
+		if err != nil && !isUnknownMessage(err) {
+			return err
+		}
+		return nil
+	}
+
+	msg, err := discord.ChannelMessage(post.ChannelID, post.MessageID, discordgo.WithContext(ctx))
+	if isUnknownMessage(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	content, embeds := strikeThrough(msg.Content, msg.Embeds)
+	edit := &discordgo.MessageEdit{
+		ID:      post.MessageID,
+		Channel: post.ChannelID,
+		Content: &content,
+		Embeds:  &embeds,
+	}
+	if len(embeds) == 0 {
+		// Hide the link preview, which still shows the deleted post
+		edit.Flags = discordgo.MessageFlagsSuppressEmbeds
+	}
+
+	_, err = discord.ChannelMessageEditComplex(edit, discordgo.WithContext(ctx))
+	return err
+}
+
+// strikeThrough marks the content and embeds of a relayed message as deleted.
+func strikeThrough(content string, embeds []*discordgo.MessageEmbed) (string, []*discordgo.MessageEmbed) {
+	if content != "" {
+		content = fmt.Sprintf("~~%s~~ *(deleted)*", strings.TrimSpace(content))
+	}
+
+	ret := []*discordgo.MessageEmbed{}
+	for _, embed := range embeds {
+		// Only our own embeds are kept, as link previews are regenerated by
+		// Discord from the content and cannot be edited
+		if embed.Type != discordgo.EmbedTypeRich {
+			continue
+		}
+
+		struck := *embed
+		struck.Title = "Deleted"
+		if struck.Description != "" {
+			struck.Description = "~~" + truncate(struck.Description, 4092) + "~~"
+		}
+		ret = append(ret, &struck)
+	}
+
+	return content, ret
+}
+
+// isUnknownMessage reports whether the Discord message no longer exists, such
+// as when it was deleted by a moderator.
+func isUnknownMessage(err error) bool {
+	var restErr *discordgo.RESTError
+	return errors.As(err, &restErr) && restErr.Response != nil && restErr.Response.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/taiidani/no-time-to-explain/internal/db/models"
+)
+
+func Test_strikeThrough(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		embeds      []*discordgo.MessageEmbed
+		wantContent string
+		wantEmbeds  []discordgo.MessageEmbed
+	}{
+		{
+			name:        "link relay",
+			content:     "https://bsky.app/profile/example.com/post/1",
+			embeds:      []*discordgo.MessageEmbed{{Type: discordgo.EmbedTypeLink, Description: "Link preview"}},
+			wantContent: "~~https://bsky.app/profile/example.com/post/1~~ *(deleted)*",
+			wantEmbeds:  []discordgo.MessageEmbed{},
+		},
+		{
+			name:        "rich embed relay",
+			embeds:      []*discordgo.MessageEmbed{{Type: discordgo.EmbedTypeRich, Title: "View on Bluesky", Description: "Servers are down"}},
+			wantContent: "",
+			wantEmbeds:  []discordgo.MessageEmbed{{Type: discordgo.EmbedTypeRich, Title: "Deleted", Description: "~~Servers are down~~"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, embeds := strikeThrough(tt.content, tt.embeds)
+			if content != tt.wantContent {
+				t.Errorf("strikeThrough() content = %q, want %q", content, tt.wantContent)
+			}
+			if len(embeds) != len(tt.wantEmbeds) {
+				t.Fatalf("strikeThrough() embeds = %d, want %d", len(embeds), len(tt.wantEmbeds))
+			}
+			for i, embed := range embeds {
+				want := tt.wantEmbeds[i]
+				if embed.Title != want.Title || embed.Description != want.Description {
+					t.Errorf("strikeThrough() embed %d = %q %q, want %q %q", i, embed.Title, embed.Description, want.Title, want.Description)
+				}
+			}
+		})
+	}
+
+	// The original message is left untouched
+	original := &discordgo.MessageEmbed{Type: discordgo.EmbedTypeRich, Title: "View on Bluesky"}
+	strikeThrough("", []*discordgo.MessageEmbed{original})
+	if original.Title != "View on Bluesky" {
+		t.Errorf("strikeThrough() modified the original embed")
+	}
+}
+
+func Test_mirrorEdit(t *testing.T) {
+	post := models.FeedPost{ChannelID: "1", MessageID: "2"}
+
+	tests := []struct {
+		name       string
+		feed       models.Feed
+		entry      feedEntry
+		wantEmbeds bool
+	}{
+		{
+			name:  "link relay",
+			entry: feedEntry{Message: &discordgo.MessageSend{Content: "https://bsky.app/profile/example.com/post/1"}},
+		},
+		{
+			name:       "rich embed relay",
+			feed:       models.Feed{RichEmbed: true},
+			entry:      feedEntry{Message: &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{{Title: "View on Bluesky"}}}},
+			wantEmbeds: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(mirrorEdit(tt.feed, post, tt.entry))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := strings.Contains(string(data), `"embeds"`); got != tt.wantEmbeds {
+				t.Errorf("mirrorEdit() = %s, want embeds sent %v", data, tt.wantEmbeds)
+			}
+		})
+	}
+}
@@ -3,10 +3,13 @@ package internal
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"regexp"
 	"slices"
+	"strconv"
 	"sync"
 	"time"
 
@@ -20,7 +23,10 @@ import (
 // tracer is the OpenTelemetry tracer for the background refresh job.
 var tracer = otel.Tracer("github.com/taiidani/no-time-to-explain/internal")
 
-func Refresh(ctx context.Context, conn *sql.DB, discord *discordgo.Session) error {
+// Refresh polls every feed for new entries, relaying them to Discord. Bluesky
+// feeds are skipped while the given Streamer is connected, since it relays their
+// posts as they are published. A nil Streamer polls every feed.
+func Refresh(ctx context.Context, conn *sql.DB, discord *discordgo.Session, streamer *Streamer) error {
 	ctx, span := tracer.Start(ctx, "refresh")
 	defer span.End()
 
@@ -29,25 +35,25 @@ func Refresh(ctx context.Context, conn *sql.DB, discord *discordgo.Session) erro
 	wg := sync.WaitGroup{}
 
 	wg.Go(func() {
-		slog.InfoContext(ctx, "starting bluesky refresh")
-		err := refreshBlueskyFeeds(ctx, queries, discord)
+		slog.InfoContext(ctx, "starting feed refresh")
+		err := refreshFeeds(ctx, queries, discord, newFeedSources(), streamer)
 		if err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, err.Error())
-			slog.ErrorContext(ctx, "bluesky refresh error", "err", err)
+			slog.ErrorContext(ctx, "feed refresh error", "err", err)
 			return
 		}
-		slog.InfoContext(ctx, "bluesky refresh complete")
+		slog.InfoContext(ctx, "feed refresh complete")
 	})
 
 	wg.Wait()
 	return nil
 }
 
-// refreshBlueskyFeeds will post all Bluesky messages since the last processing time
-// to the associated Discord channel.
-func refreshBlueskyFeeds(ctx context.Context, queries *models.Queries, discord *discordgo.Session) (err error) {
-	ctx, span := tracer.Start(ctx, "refresh-bluesky")
+// refreshFeeds will post all feed entries since the last processing time to the
+// associated Discord channel.
+func refreshFeeds(ctx context.Context, queries *models.Queries, discord *discordgo.Session, sources map[string]feedSource, streamer *Streamer) (err error) {
+	ctx, span := tracer.Start(ctx, "refresh-feeds")
 	defer func() {
 		if err != nil {
 			span.RecordError(err)
@@ -56,72 +62,322 @@ func refreshBlueskyFeeds(ctx context.Context, queries *models.Queries, discord *
 		span.End()
 	}()
 
-	// Examine the Bluesky posts
-	bs := bluesky.NewBlueskyClient()
-
 	feeds, err := queries.LoadFeeds(ctx)
 	if err != nil {
 		return fmt.Errorf("feed load error: %w", err)
 	}
 
+	// Each feed is refreshed independently, so that one failing feed cannot
+	// hold up the others
+	var errs error
 	for _, feed := range feeds {
-		logger := slog.With("author", feed.Author)
+		logger := slog.With("source", feed.Source, "author", feed.Author)
 
-		userFeed, err := bs.GetUserFeed(feed.AuthorSourceID)
-		if err != nil {
-			return fmt.Errorf("user feed error: %w", err)
+		source, found := sources[feed.Source]
+		if !found {
+			logger.Warn("skipping feed with unknown source")
+			continue
 		}
 
-		newPosts := filterPosts(logger, feed, userFeed.Feed)
-		if len(newPosts) == 0 {
-			logger.Info("no new bluesky posts since the last processing time")
-			continue
+		var err error
+		if streamer.Streaming(feed) {
+			logger.Debug("skipping feed relayed by the stream")
+		} else {
+			err = refreshFeed(ctx, logger, queries, discord, source, feed)
 		}
 
-		// Reverse the order of the posts so they are in chronological order
-		slices.Reverse(newPosts)
+		// Deletions and edits are only visible by polling, so are mirrored
+		// for streamed feeds as well
+		if syncer, ok := source.(feedSyncer); ok && feed.Mirror != models.MirrorNone {
+			err = errors.Join(err, mirrorFeed(ctx, logger, queries, discord, syncer, feed))
+		}
+
+		recordFeedHealth(ctx, logger, queries, feed, err)
+		errs = errors.Join(errs, err)
+	}
+
+	pruneFeedPosts(ctx, queries)
+
+	return errs
+}
+
+// refreshFeed posts the feed's entries since its last processing time to its
+// Discord channel, then records the new processing time.
+func refreshFeed(ctx context.Context, logger *slog.Logger, queries *models.Queries, discord *discordgo.Session, source feedSource, feed models.Feed) error {
+	var entries []feedEntry
+	err := retry(ctx, logger, retryAttempts, retryBaseDelay, func() (err error) {
+		entries, err = source.Entries(ctx, logger, feed)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s feed %s error: %w", feed.Source, feed.Author, err)
+	}
+
+	if len(entries) == 0 {
+		logger.Info("no new feed entries since the last processing time")
+		return nil
+	}
+
+	// Only relay the most recent entries, so that a feed which has fallen far
+	// behind cannot flood the channel
+	maxPosts := envInt("FEED_MAX_POSTS", defaultMaxPosts)
+	if maxPosts > 0 && len(entries) > maxPosts {
+		logger.Warn("too many new feed entries, skipping the oldest",
+			"new_entries", len(entries),
+			"max_posts", maxPosts,
+		)
+		entries = entries[:maxPosts]
+	}
 
-		channelID := os.Getenv("BLUESKY_FEED_CHANNEL_ID")
-		for _, post := range newPosts {
-			_, err := discord.ChannelMessageSend(channelID, post.Post.URL(), discordgo.WithContext(ctx))
-			if err != nil {
-				return fmt.Errorf("posting error: %w", err)
-			}
+	// Reverse the order of the entries so they are in chronological order
+	slices.Reverse(entries)
 
-			// Mark this as the most recent feed entry we've processed
-			if post.Post.IndexedAt.After(feed.LastMessage) {
-				feed.LastMessage = post.Post.IndexedAt
-			}
+	channelID := feedChannelID(feed)
+	lastMessage := feed.LastMessage
+	var postErr error
+	for _, entry := range entries {
+		postErr = relayEntry(ctx, logger, queries, discord, feed, channelID, entry)
+		if postErr != nil {
+			postErr = fmt.Errorf("posting error for feed %s: %w", feed.Author, postErr)
+			break
 		}
 
-		// Record the most recent post into the DB for the next run
-		_, err = queries.UpdateFeed(ctx, models.UpdateFeedParams{
-			ID:             feed.ID,
-			Source:         feed.Source,
-			Author:         feed.Author,
-			AuthorSourceID: feed.AuthorSourceID,
-			LastMessage:    feed.LastMessage,
+		// Mark this as the most recent feed entry we've processed
+		if entry.PublishedAt.After(lastMessage) {
+			lastMessage = entry.PublishedAt
+		}
+	}
+
+	// Record the most recent entry into the DB for the next run, including when
+	// posting failed part way so that the posted entries are not repeated
+	if lastMessage.After(feed.LastMessage) {
+		err = queries.UpdateFeedLastMessage(ctx, models.UpdateFeedLastMessageParams{
+			ID:          feed.ID,
+			LastMessage: lastMessage,
 		})
 		if err != nil {
-			return fmt.Errorf("failed to update feed %s in db: %w", feed.Author, err)
+			return errors.Join(postErr, fmt.Errorf("failed to update feed %s in db: %w", feed.Author, err))
+		}
+	}
+
+	return postErr
+}
+
+// feedLedger records the entries relayed from feeds, as models.Queries does.
+type feedLedger interface {
+	ClaimFeedPost(ctx context.Context, arg models.ClaimFeedPostParams) (int64, error)
+	ReleaseFeedPost(ctx context.Context, arg models.ReleaseFeedPostParams) error
+	UpdateFeedPostMessage(ctx context.Context, arg models.UpdateFeedPostMessageParams) error
+}
+
+// relayEntry posts the entry to the channel unless the ledger shows that it was
+// already relayed. The entry is claimed in the ledger before it is sent, so that
+// a refresh and the streamer relaying the same feed cannot both post it, and the
+// resulting message is recorded against the claim. Claims left unsent for longer
+// than feedPostClaimTimeout, such as by a bot that was stopped mid-send, are
+// taken over so that their entries are not lost.
+//
+// Transient send errors are retried with backoff while the claim is held, so
+// that no other run can post the entry in the meantime. An entry whose send
+// still fails has its claim released so that the next refresh tries it again.
+func relayEntry(ctx context.Context, logger *slog.Logger, queries feedLedger, discord *discordgo.Session, feed models.Feed, channelID string, entry feedEntry) error {
+	entryLogger := logger.With("entry_id", entry.ID)
+
+	claimed, err := queries.ClaimFeedPost(ctx, models.ClaimFeedPostParams{
+		FeedID:      feed.ID,
+		PostUri:     entry.ID,
+		PostCid:     entry.CID,
+		ChannelID:   channelID,
+		PublishedAt: entry.PublishedAt,
+		ClaimedAt:   time.Now().Add(-feedPostClaimTimeout),
+	})
+	if err != nil {
+		return fmt.Errorf("could not claim feed entry in ledger: %w", err)
+	} else if claimed == 0 {
+		entryLogger.Debug("skipping already relayed feed entry")
+		return nil
+	}
+
+	var msg *discordgo.Message
+	err = retry(ctx, entryLogger, retryAttempts, retryBaseDelay, func() (err error) {
+		msg, err = discord.ChannelMessageSendComplex(channelID, entry.Message, discordgo.WithContext(ctx))
+		return err
+	})
+	if err != nil {
+		err = fmt.Errorf("could not relay feed entry: %w", err)
+		// Use a fresh context, as a cancelled refresh must still release its claim
+		releaseErr := queries.ReleaseFeedPost(context.WithoutCancel(ctx), models.ReleaseFeedPostParams{
+			FeedID:  feed.ID,
+			PostUri: entry.ID,
+		})
+		if releaseErr != nil {
+			err = errors.Join(err, fmt.Errorf("could not release feed entry in ledger: %w", releaseErr))
 		}
+		return err
+	}
+
+	// The message has been sent, so a failure to record it only prevents the
+	// message from being mirrored
+	err = queries.UpdateFeedPostMessage(ctx, models.UpdateFeedPostMessageParams{
+		FeedID:    feed.ID,
+		PostUri:   entry.ID,
+		MessageID: msg.ID,
+	})
+	if err != nil {
+		entryLogger.ErrorContext(ctx, "failed to record relayed feed entry", "message_id", msg.ID, "err", err)
 	}
 
 	return nil
 }
 
+// pruneFeedPosts removes ledger entries that are older than any entry that could
+// still be relayed.
+func pruneFeedPosts(ctx context.Context, queries *models.Queries) {
+	pruned, err := queries.PruneFeedPosts(ctx, time.Now().Add(-feedPostRetention))
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to prune feed ledger", "err", err)
+		return
+	}
+
+	if pruned > 0 {
+		slog.InfoContext(ctx, "pruned feed ledger", "entries", pruned)
+	}
+}
+
+// recordFeedHealth stores the outcome of a feed's refresh, so that failing feeds
+// can be spotted from the admin page.
+func recordFeedHealth(ctx context.Context, logger *slog.Logger, queries *models.Queries, feed models.Feed, refreshErr error) {
+	var err error
+	if refreshErr != nil {
+		logger.ErrorContext(ctx, "feed refresh error", "err", refreshErr, "consecutive_failures", feed.ConsecutiveFailures+1)
+		err = queries.RecordFeedFailure(ctx, models.RecordFeedFailureParams{
+			ID:        feed.ID,
+			LastError: refreshErr.Error(),
+		})
+	} else if feed.ConsecutiveFailures > 0 {
+		logger.InfoContext(ctx, "feed recovered", "consecutive_failures", feed.ConsecutiveFailures)
+		err = queries.ResetFeedFailures(ctx, feed.ID)
+	}
+
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to record feed health", "err", err)
+	}
+}
+
+const (
+	// defaultCatchupMaxPages is the default number of author feed pages that will
+	// be read while catching up to the last processed post.
+	defaultCatchupMaxPages = 5
+
+	// feedPostRetention is how long relayed entries are kept in the ledger.
+	feedPostRetention = 30 * 24 * time.Hour
+
+	// feedPostClaimTimeout is how long an entry claimed for relaying may go
+	// unsent before another run takes it over. It is well beyond the time that
+	// a send and its retries can take.
+	feedPostClaimTimeout = 10 * time.Minute
+
+	// defaultMaxPosts is the default number of entries that will be relayed for
+	// a single feed in a single refresh.
+	defaultMaxPosts = 25
+
+	// catchupPageSize is the number of entries requested per author feed page.
+	catchupPageSize = 50
+)
+
+// fetchBlueskyPosts pages through the author feed, newest first, until it reaches
+// an entry that was already processed or has read maxPages pages. The entries
+// of every page read are returned in the order they were received.
+func fetchBlueskyPosts(bs *bluesky.BlueskyClient, feed models.Feed, maxPages int) ([]bluesky.FeedPostEntry, error) {
+	ret := []bluesky.FeedPostEntry{}
+
+	opts := bluesky.FeedOptions{Limit: catchupPageSize}
+	for page := 0; page < max(maxPages, 1); page++ {
+		userFeed, err := bs.GetUserFeed(feed.AuthorSourceID, opts)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, userFeed.Feed...)
+
+		// Stop once we've passed the last processed entry or run out of pages
+		caughtUp := slices.ContainsFunc(userFeed.Feed, func(entry bluesky.FeedPostEntry) bool {
+			return !entry.SortAt().After(feed.LastMessage)
+		})
+		if caughtUp || userFeed.Cursor == "" || len(userFeed.Feed) == 0 {
+			break
+		}
+		opts.Cursor = userFeed.Cursor
+	}
+
+	return ret, nil
+}
+
+// envInt reads an integer from the given environment variable, falling back upon
+// the provided default if it is unset or invalid.
+func envInt(key string, fallback int) int {
+	val, found := os.LookupEnv(key)
+	if !found {
+		return fallback
+	}
+
+	ret, err := strconv.Atoi(val)
+	if err != nil {
+		slog.Warn("Invalid integer environment variable, using default", "key", key, "value", val, "default", fallback)
+		return fallback
+	}
+
+	return ret
+}
+
+// envDuration reads a duration such as "12h" from the given environment
+// variable, falling back upon the provided default if it is unset or invalid.
+func envDuration(key string, fallback time.Duration) time.Duration {
+	val, found := os.LookupEnv(key)
+	if !found {
+		return fallback
+	}
+
+	ret, err := time.ParseDuration(val)
+	if err != nil {
+		slog.Warn("Invalid duration environment variable, using default", "key", key, "value", val, "default", fallback)
+		return fallback
+	}
+
+	return ret
+}
+
+// feedChannelID returns the Discord channel that the given feed should be posted
+// to, falling back upon the BLUESKY_FEED_CHANNEL_ID environment variable for
+// feeds that have not been assigned a channel.
+func feedChannelID(feed models.Feed) string {
+	if feed.ChannelID != "" {
+		return feed.ChannelID
+	}
+
+	return os.Getenv("BLUESKY_FEED_CHANNEL_ID")
+}
+
+// repostReason is the `FeedPostReason` type of entries that were reposted by the
+// author rather than posted by them.
+const repostReason = "app.bsky.feed.defs#reasonRepost"
+
+// filterPosts returns the entries of the author feed that should be relayed,
+// according to the feed's last processed time and content rules.
 func filterPosts(logger *slog.Logger, feed models.Feed, posts []bluesky.FeedPostEntry) []bluesky.FeedPostEntry {
 	ret := []bluesky.FeedPostEntry{}
 
+	allow := compilePatterns(logger, feed.KeywordAllowPatterns())
+	block := compilePatterns(logger, feed.KeywordBlockPatterns())
+
 	for _, post := range posts {
 		postLogger := logger.With(
 			"post_uri", post.Post.URI,
 			"post_indexed_at", post.Post.IndexedAt,
 		)
 
-		// Skip reposts. The author feed can include reposted entries, which causes the
-		// same underlying post to be emitted again on later refreshes.
-		if post.Reason != nil && post.Reason.Type == "app.bsky.feed.defs#reasonRepost" {
+		// Skip reposts unless the feed wants them. Reposts are tracked by the time
+		// of the repost, so the same underlying post is only emitted once.
+		if post.Reason != nil && post.Reason.Type == repostReason && !feed.IncludeReposts {
 			postLogger.Debug("skipping bluesky repost entry",
 				"reason_indexed_at", post.Reason.IndexedAt,
 			)
@@ -130,22 +386,72 @@ func filterPosts(logger *slog.Logger, feed models.Feed, posts []bluesky.FeedPost
 
 		// Skip posts from within the last minute
 		// If we display before the embeds have been processed then they might not get
-		// added to the message.
-		if post.Post.IndexedAt.After(time.Now().Add(time.Minute * -1)) {
+		// added to the message. Rich embeds are rendered by us and need not wait.
+		if !feed.RichEmbed && post.Post.IndexedAt.After(time.Now().Add(time.Minute*-1)) {
 			postLogger.Debug("skipping recent bluesky post")
 			continue
 		}
 
 		// Has the feed entry already been processed?
-		if post.Post.IndexedAt.Before(feed.LastMessage) || post.Post.IndexedAt.Equal(feed.LastMessage) {
+		if !post.SortAt().After(feed.LastMessage) {
 			postLogger.Debug("skipping already processed bluesky post",
 				"last_message", feed.LastMessage,
 			)
 			continue
 		}
 
+		if !feed.IncludeReplies && post.Post.IsReply() {
+			postLogger.Debug("skipping bluesky reply")
+			continue
+		}
+
+		if !feed.IncludeQuotes && post.Post.IsQuote() {
+			postLogger.Debug("skipping bluesky quote post")
+			continue
+		}
+
+		if feed.MediaOnly && !post.Post.HasMedia() {
+			postLogger.Debug("skipping bluesky post without media")
+			continue
+		}
+
+		if len(allow) > 0 && !matchesAny(allow, post.Post.Record.Text) {
+			postLogger.Debug("skipping bluesky post not matching the keyword allowlist")
+			continue
+		}
+
+		if matchesAny(block, post.Post.Record.Text) {
+			postLogger.Debug("skipping bluesky post matching the keyword blocklist")
+			continue
+		}
+
 		ret = append(ret, post)
 	}
 
 	return ret
 }
+
+// compilePatterns compiles the given regular expressions, skipping any that are
+// invalid. Patterns are validated when the feed is saved, so this should only
+// happen for rows that predate the validation.
+func compilePatterns(logger *slog.Logger, patterns []string) []*regexp.Regexp {
+	ret := []*regexp.Regexp{}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			logger.Warn("skipping invalid feed keyword pattern", "pattern", pattern, "err", err)
+			continue
+		}
+		ret = append(ret, re)
+	}
+	return ret
+}
+
+func matchesAny(patterns []*regexp.Regexp, text string) bool {
+	for _, re := range patterns {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,11 +1,17 @@
 package internal
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/bwmarrin/discordgo"
 	"github.com/taiidani/no-time-to-explain/internal/bluesky"
 	"github.com/taiidani/no-time-to-explain/internal/db/models"
 )
@@ -49,6 +55,51 @@ func Test_filterPosts(t *testing.T) {
 		},
 	}
 
+	postReply := bluesky.FeedPost{
+		IndexedAt: tm.Add(time.Minute * -5),
+		Record: bluesky.FeedPostRecord{
+			Text:  "We are aware of an issue",
+			Reply: &bluesky.PostReply{},
+		},
+	}
+	postQuote := bluesky.FeedPost{
+		IndexedAt: tm.Add(time.Minute * -6),
+		Embed: &bluesky.PostEmbedView{
+			Type:   bluesky.EmbedRecordView,
+			Record: &bluesky.EmbedRecordViewData{URI: "at://did:plc:abc/app.bsky.feed.post/1"},
+		},
+		Record: bluesky.FeedPostRecord{Text: "Maintenance is complete"},
+	}
+	postMedia := bluesky.FeedPost{
+		IndexedAt: tm.Add(time.Minute * -7),
+		Embed: &bluesky.PostEmbedView{
+			Type:   bluesky.EmbedImagesView,
+			Images: []bluesky.EmbedImageView{{Fullsize: "https://cdn/full.jpg"}},
+		},
+		Record: bluesky.FeedPostRecord{Text: "New Season artwork"},
+	}
+	rules := []bluesky.FeedPostEntry{
+		{Post: postReply},
+		{Post: postQuote},
+		{Post: postMedia},
+		{Post: postHourAgo},
+	}
+	repostOfOldPost := bluesky.FeedPostEntry{
+		Post: bluesky.FeedPost{
+			IndexedAt: tm.Add(time.Hour * -48),
+			Record:    bluesky.FeedPostRecord{Text: "old post"},
+		},
+		Reason: &bluesky.FeedPostReason{
+			Type:      "app.bsky.feed.defs#reasonRepost",
+			IndexedAt: tm.Add(time.Minute * -10),
+		},
+	}
+	includeAll := models.Feed{
+		LastMessage:    lastMessage,
+		IncludeReplies: true,
+		IncludeQuotes:  true,
+	}
+
 	type args struct {
 		feed  models.Feed
 		posts []bluesky.FeedPostEntry
@@ -76,6 +127,20 @@ func Test_filterPosts(t *testing.T) {
 				{Post: postHourAgo},
 			},
 		},
+		{
+			name: "rich embeds do not wait for recent posts",
+			args: args{
+				feed: models.Feed{LastMessage: lastMessage, RichEmbed: true},
+				posts: []bluesky.FeedPostEntry{
+					{Post: postSecondAgo},
+					{Post: postHourAgo},
+				},
+			},
+			want: []bluesky.FeedPostEntry{
+				{Post: postSecondAgo},
+				{Post: postHourAgo},
+			},
+		},
 		{
 			name: "filters repost entries",
 			args: args{
@@ -94,6 +159,92 @@ func Test_filterPosts(t *testing.T) {
 				{Post: postTwoMinuteAgo},
 			},
 		},
+		{
+			name: "includes reposts by repost time",
+			args: args{
+				feed: models.Feed{LastMessage: lastMessage, IncludeReposts: true},
+				posts: []bluesky.FeedPostEntry{
+					repostOfOldPost,
+					{Post: postHourAgo},
+				},
+			},
+			want: []bluesky.FeedPostEntry{
+				repostOfOldPost,
+				{Post: postHourAgo},
+			},
+		},
+		{
+			name: "includes replies and quotes",
+			args: args{
+				feed:  includeAll,
+				posts: rules,
+			},
+			want: rules,
+		},
+		{
+			name: "filters replies",
+			args: args{
+				feed:  models.Feed{LastMessage: lastMessage, IncludeQuotes: true},
+				posts: rules,
+			},
+			want: []bluesky.FeedPostEntry{
+				{Post: postQuote},
+				{Post: postMedia},
+				{Post: postHourAgo},
+			},
+		},
+		{
+			name: "filters quotes",
+			args: args{
+				feed:  models.Feed{LastMessage: lastMessage, IncludeReplies: true},
+				posts: rules,
+			},
+			want: []bluesky.FeedPostEntry{
+				{Post: postReply},
+				{Post: postMedia},
+				{Post: postHourAgo},
+			},
+		},
+		{
+			name: "media only",
+			args: args{
+				feed:  models.Feed{LastMessage: lastMessage, IncludeReplies: true, IncludeQuotes: true, MediaOnly: true},
+				posts: rules,
+			},
+			want: []bluesky.FeedPostEntry{
+				{Post: postMedia},
+			},
+		},
+		{
+			name: "keyword allowlist",
+			args: args{
+				feed:  models.Feed{LastMessage: lastMessage, IncludeReplies: true, IncludeQuotes: true, KeywordAllow: "(?i)maintenance\n[Ss]eason"},
+				posts: rules,
+			},
+			want: []bluesky.FeedPostEntry{
+				{Post: postQuote},
+				{Post: postMedia},
+			},
+		},
+		{
+			name: "keyword blocklist",
+			args: args{
+				feed:  models.Feed{LastMessage: lastMessage, IncludeReplies: true, IncludeQuotes: true, KeywordBlock: "issue\n\nago"},
+				posts: rules,
+			},
+			want: []bluesky.FeedPostEntry{
+				{Post: postQuote},
+				{Post: postMedia},
+			},
+		},
+		{
+			name: "invalid keyword patterns are ignored",
+			args: args{
+				feed:  models.Feed{LastMessage: lastMessage, IncludeReplies: true, IncludeQuotes: true, KeywordBlock: "[invalid"},
+				posts: rules,
+			},
+			want: rules,
+		},
 		{
 			name: "filters already processed by indexed time",
 			args: args{
@@ -114,3 +265,224 @@ func Test_filterPosts(t *testing.T) {
 		})
 	}
 }
+
+func Test_feedChannelID(t *testing.T) {
+	t.Setenv("BLUESKY_FEED_CHANNEL_ID", "default-channel")
+
+	tests := []struct {
+		name string
+		feed models.Feed
+		want string
+	}{
+		{
+			name: "feed channel",
+			feed: models.Feed{ChannelID: "feed-channel"},
+			want: "feed-channel",
+		},
+		{
+			name: "fallback",
+			feed: models.Feed{},
+			want: "default-channel",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := feedChannelID(tt.feed); got != tt.want {
+				t.Errorf("feedChannelID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_fetchBlueskyPosts(t *testing.T) {
+	tm := time.Now()
+	entry := func(age time.Duration) bluesky.FeedPostEntry {
+		return bluesky.FeedPostEntry{Post: bluesky.FeedPost{IndexedAt: tm.Add(-age)}}
+	}
+
+	// Three pages of two entries each, newest first
+	pages := map[string]bluesky.FeedData{
+		"":   {Feed: []bluesky.FeedPostEntry{entry(time.Hour), entry(2 * time.Hour)}, Cursor: "p2"},
+		"p2": {Feed: []bluesky.FeedPostEntry{entry(3 * time.Hour), entry(4 * time.Hour)}, Cursor: "p3"},
+		"p3": {Feed: []bluesky.FeedPostEntry{entry(5 * time.Hour), entry(6 * time.Hour)}},
+	}
+
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Get("limit") != "50" {
+			t.Errorf("limit = %q, want 50", r.URL.Query().Get("limit"))
+		}
+		_ = json.NewEncoder(w).Encode(pages[r.URL.Query().Get("cursor")])
+	}))
+	defer srv.Close()
+
+	bs := bluesky.NewBlueskyClient()
+	bs.BaseURL = srv.URL
+
+	tests := []struct {
+		name         string
+		lastMessage  time.Time
+		maxPages     int
+		wantEntries  int
+		wantRequests int
+	}{
+		{
+			name:         "caught up on first page",
+			lastMessage:  tm.Add(-90 * time.Minute),
+			maxPages:     5,
+			wantEntries:  2,
+			wantRequests: 1,
+		},
+		{
+			name:         "pages until last message",
+			lastMessage:  tm.Add(-210 * time.Minute),
+			maxPages:     5,
+			wantEntries:  4,
+			wantRequests: 2,
+		},
+		{
+			name:         "stops at last page",
+			lastMessage:  tm.Add(-24 * time.Hour),
+			maxPages:     5,
+			wantEntries:  6,
+			wantRequests: 3,
+		},
+		{
+			name:         "capped by max pages",
+			lastMessage:  tm.Add(-24 * time.Hour),
+			maxPages:     2,
+			wantEntries:  4,
+			wantRequests: 2,
+		},
+		{
+			name:         "catch-up disabled",
+			lastMessage:  tm.Add(-24 * time.Hour),
+			maxPages:     0,
+			wantEntries:  2,
+			wantRequests: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests = 0
+			got, err := fetchBlueskyPosts(bs, models.Feed{LastMessage: tt.lastMessage}, tt.maxPages)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.wantEntries {
+				t.Errorf("fetchBlueskyPosts() returned %d entries, want %d", len(got), tt.wantEntries)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("fetchBlueskyPosts() made %d requests, want %d", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+// memoryLedger is a feedLedger that claims entries as the ledger queries do.
+type memoryLedger map[string]*models.FeedPost
+
+func (l memoryLedger) ClaimFeedPost(_ context.Context, arg models.ClaimFeedPostParams) (int64, error) {
+	if post, found := l[arg.PostUri]; found && (post.MessageID != "" || !post.ClaimedAt.Before(arg.ClaimedAt)) {
+		return 0, nil
+	}
+	l[arg.PostUri] = &models.FeedPost{FeedID: arg.FeedID, PostUri: arg.PostUri, ChannelID: arg.ChannelID, ClaimedAt: time.Now()}
+	return 1, nil
+}
+
+func (l memoryLedger) ReleaseFeedPost(_ context.Context, arg models.ReleaseFeedPostParams) error {
+	if post, found := l[arg.PostUri]; found && post.MessageID == "" {
+		delete(l, arg.PostUri)
+	}
+	return nil
+}
+
+func (l memoryLedger) UpdateFeedPostMessage(_ context.Context, arg models.UpdateFeedPostMessageParams) error {
+	if post, found := l[arg.PostUri]; found {
+		post.MessageID = arg.MessageID
+	}
+	return nil
+}
+
+func Test_relayEntry(t *testing.T) {
+	entry := feedEntry{ID: "at://example/post/1", Message: &discordgo.MessageSend{Content: "https://example.com/1"}}
+
+	tests := []struct {
+		name        string
+		ledger      memoryLedger
+		status      int
+		wantSent    bool
+		wantErr     bool
+		wantMessage string
+	}{
+		{
+			name:        "new entry",
+			ledger:      memoryLedger{},
+			wantSent:    true,
+			wantMessage: "10",
+		},
+		{
+			name:        "already relayed",
+			ledger:      memoryLedger{entry.ID: {MessageID: "9", ClaimedAt: time.Now().Add(-time.Hour)}},
+			wantMessage: "9",
+		},
+		{
+			name:   "claimed by another run",
+			ledger: memoryLedger{entry.ID: {ClaimedAt: time.Now()}},
+		},
+		{
+			name:        "stale claim",
+			ledger:      memoryLedger{entry.ID: {ClaimedAt: time.Now().Add(-feedPostClaimTimeout - time.Minute)}},
+			wantSent:    true,
+			wantMessage: "10",
+		},
+		{
+			name:     "send fails",
+			ledger:   memoryLedger{},
+			status:   http.StatusForbidden,
+			wantSent: true,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := false
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				sent = true
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+					_, _ = fmt.Fprint(w, `{"message": "Missing Permissions", "code": 50013}`)
+					return
+				}
+				_, _ = fmt.Fprint(w, `{"id": "10"}`)
+			}))
+			defer srv.Close()
+
+			previous := discordgo.EndpointChannels
+			discordgo.EndpointChannels = srv.URL + "/channels/"
+			t.Cleanup(func() { discordgo.EndpointChannels = previous })
+
+			discord, err := discordgo.New("Bot token")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = relayEntry(t.Context(), slog.Default(), tt.ledger, discord, models.Feed{ID: 1}, "20", entry)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("relayEntry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if sent != tt.wantSent {
+				t.Errorf("relayEntry() sent = %v, want %v", sent, tt.wantSent)
+			}
+
+			post, found := tt.ledger[entry.ID]
+			switch {
+			case tt.wantErr && found:
+				t.Errorf("relayEntry() left the claim %+v after failing", post)
+			case !tt.wantErr && (!found || post.MessageID != tt.wantMessage):
+				t.Errorf("relayEntry() ledger = %+v, want message %q", post, tt.wantMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Variables that are always available to a response.
+const (
+	VarMention = "mention"
+	VarName    = "name"
+	VarChannel = "channel"
+	VarTime    = "time"
+)
+
+// Template is a parsed auto-response. Variables are written in braces, such as
+// "Hello {name}!", and a literal brace is written by doubling it. Besides the
+// Var constants, the capture groups of the trigger may be referenced by number,
+// such as {1}, or by name for named groups.
+type Template struct {
+	parts []part
+}
+
+type part struct {
+	text     string
+	variable string
+}
+
+// Vars are the values substituted into a Template when it is rendered.
+type Vars struct {
+	// SenderID is the Discord user ID of the sender, used to mention them.
+	SenderID string
+
+	// Name is the sender's display name.
+	Name string
+
+	// Channel is the name of the channel that the trigger was sent to.
+	Channel string
+
+	// Now is rendered as a relative Discord timestamp.
+	Now time.Time
+
+	// Groups are the capture groups of the trigger, as returned by
+	// `regexp.Regexp.FindStringSubmatch`.
+	Groups []string
+
+	// GroupNames are the names of the capture groups, as returned by
+	// `regexp.Regexp.SubexpNames`.
+	GroupNames []string
+}
+
+// variablePattern matches the names that may be used for variables.
+var variablePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// Parse parses the given response text.
+func Parse(text string) (*Template, error) {
+	ret := &Template{}
+
+	var literal strings.Builder
+	for i := 0; i < len(text); i++ {
+		switch {
+		case strings.HasPrefix(text[i:], "{{"), strings.HasPrefix(text[i:], "}}"):
+			literal.WriteByte(text[i])
+			i++
+		case text[i] == '{':
+			end := strings.IndexByte(text[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed variable at position %d", i)
+			}
+
+			name := strings.TrimSpace(text[i+1 : i+end])
+			if !variablePattern.MatchString(name) {
+				return nil, fmt.Errorf("invalid variable name %q", name)
+			}
+
+			if literal.Len() > 0 {
+				ret.parts = append(ret.parts, part{text: literal.String()})
+				literal.Reset()
+			}
+			ret.parts = append(ret.parts, part{variable: name})
+			i += end
+		case text[i] == '}':
+			return nil, fmt.Errorf("unexpected } at position %d, use }} for a literal brace", i)
+		default:
+			literal.WriteByte(text[i])
+		}
+	}
+
+	if literal.Len() > 0 {
+		ret.parts = append(ret.parts, part{text: literal.String()})
+	}
+
+	return ret, nil
+}
+
+// Literal creates a template that renders the given text verbatim.
+func Literal(text string) *Template {
+	return &Template{parts: []part{{text: text}}}
+}
+
+// Validate verifies that every variable in the template is known, including
+// that any capture groups it references exist in the given trigger.
+func (t *Template) Validate(trigger *regexp.Regexp) error {
+	var ret error
+
+	for _, variable := range t.Variables() {
+		switch variable {
+		case VarMention, VarName, VarChannel, VarTime:
+			continue
+		}
+
+		if n, err := strconv.Atoi(variable); err == nil {
+			if n > trigger.NumSubexp() {
+				ret = errors.Join(ret, fmt.Errorf("variable {%s} refers to a capture group that the trigger does not have", variable))
+			}
+			continue
+		}
+
+		if trigger.SubexpIndex(variable) < 0 {
+			ret = errors.Join(ret, fmt.Errorf("unknown variable {%s}", variable))
+		}
+	}
+
+	return ret
+}
+
+// Variables returns the names of the variables used by the template, in order.
+func (t *Template) Variables() []string {
+	ret := []string{}
+	for _, p := range t.parts {
+		if p.variable != "" {
+			ret = append(ret, p.variable)
+		}
+	}
+
+	return ret
+}
+
+// Uses reports whether the template references the given variable, so that
+// expensive values need only be looked up when they are needed.
+func (t *Template) Uses(variable string) bool {
+	for _, p := range t.parts {
+		if p.variable == variable {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SenderControlled reports whether the template substitutes text written by
+// the sender, such as capture groups or their display name, which could be used
+// to slip mentions into the response.
+func (t *Template) SenderControlled() bool {
+	for _, variable := range t.Variables() {
+		switch variable {
+		case VarMention, VarChannel, VarTime:
+			continue
+		}
+		return true
+	}
+
+	return false
+}
+
+// Render substitutes the given values into the template. Unknown variables and
+// groups that did not participate in the match render as empty text.
+func (t *Template) Render(vars Vars) string {
+	var ret strings.Builder
+	for _, p := range t.parts {
+		if p.variable == "" {
+			ret.WriteString(p.text)
+			continue
+		}
+
+		ret.WriteString(vars.lookup(p.variable))
+	}
+
+	return ret.String()
+}
+
+func (v Vars) lookup(variable string) string {
+	switch variable {
+	case VarMention:
+		if v.SenderID == "" {
+			return ""
+		}
+		return "<@" + v.SenderID + ">"
+	case VarName:
+		return v.Name
+	case VarChannel:
+		return v.Channel
+	case VarTime:
+		return fmt.Sprintf("<t:%d:R>", v.Now.Unix())
+	}
+
+	if n, err := strconv.Atoi(variable); err == nil {
+		if n < len(v.Groups) {
+			return v.Groups[n]
+		}
+		return ""
+	}
+
+	for n, name := range v.GroupNames {
+		if name == variable && n < len(v.Groups) {
+			return v.Groups[n]
+		}
+	}
+
+	return ""
+}
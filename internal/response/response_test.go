@@ -0,0 +1,118 @@
+package response
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestTemplate_Render(t *testing.T) {
+	trigger := regexp.MustCompile(`(?i)is (\w+) down\??(?: (?P<when>today|now))?`)
+	input := "Is Bungie down today?"
+	vars := Vars{
+		SenderID:   "1234",
+		Name:       "Guardian",
+		Channel:    "general",
+		Now:        time.Unix(1750000000, 0),
+		Groups:     trigger.FindStringSubmatch(input),
+		GroupNames: trigger.SubexpNames(),
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "plain text",
+			text: "You mean Bees-us?",
+			want: "You mean Bees-us?",
+		},
+		{
+			name: "sender",
+			text: "Hey {mention}, or should I say {name}",
+			want: "Hey <@1234>, or should I say Guardian",
+		},
+		{
+			name: "channel and time",
+			text: "Checked #{channel} {time}",
+			want: "Checked #general <t:1750000000:R>",
+		},
+		{
+			name: "capture groups",
+			text: "{1} is not down {when}, {0}",
+			want: "Bungie is not down today, Is Bungie down today",
+		},
+		{
+			name: "escaped braces",
+			text: "{{name}} is {name}",
+			want: "{name} is Guardian",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl, err := Parse(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tpl.Validate(trigger); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := tpl.Render(vars); got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	trigger := regexp.MustCompile(`^ping (\w+)$`)
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{name: "unclosed", text: "Hello {name"},
+		{name: "stray closing brace", text: "Hello name}"},
+		{name: "invalid name", text: "Hello {first name}"},
+		{name: "unknown variable", text: "Hello {nickname}"},
+		{name: "missing group", text: "Hello {2}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl, err := Parse(tt.text)
+			if err == nil {
+				err = tpl.Validate(trigger)
+			}
+			if err == nil {
+				t.Errorf("Parse(%q) succeeded, want an error", tt.text)
+			}
+		})
+	}
+}
+
+func TestTemplate_SenderControlled(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{text: "<@&30> pong", want: false},
+		{text: "Hi {mention}, it is {time} in {channel}", want: false},
+		{text: "Hello {name}", want: true},
+		{text: "You said {1}", want: true},
+		{text: "You said {word}", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			tpl, err := Parse(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := tpl.SenderControlled(); got != tt.want {
+				t.Errorf("SenderControlled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
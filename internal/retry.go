@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/taiidani/no-time-to-explain/internal/bluesky"
+	"github.com/taiidani/no-time-to-explain/internal/mastodon"
+	"github.com/taiidani/no-time-to-explain/internal/rss"
+)
+
+const (
+	// retryAttempts is the number of times a transient failure is attempted
+	// before giving up on the feed until the next refresh.
+	retryAttempts = 3
+
+	// retryBaseDelay is the wait before the first retry, doubling for each
+	// attempt after it.
+	retryBaseDelay = 2 * time.Second
+)
+
+// retry calls fn until it succeeds, returns a permanent error, or has been
+// attempted the given number of times. Transient errors are retried with
+// exponential backoff starting at the given delay.
+func retry(ctx context.Context, logger *slog.Logger, attempts int, delay time.Duration, fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= attempts || !isTransient(err) {
+			return err
+		}
+
+		logger.WarnContext(ctx, "transient error, retrying", "err", err, "attempt", attempt, "retry_in", delay)
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// isTransient reports whether the error is likely to succeed if retried, such
+// as a network timeout or a server error.
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Response != nil {
+		return transientStatus(restErr.Response.StatusCode)
+	}
+
+	var blueskyErr *bluesky.APIError
+	if errors.As(err, &blueskyErr) {
+		return transientStatus(blueskyErr.StatusCode)
+	}
+
+	var mastodonErr *mastodon.APIError
+	if errors.As(err, &mastodonErr) {
+		return transientStatus(mastodonErr.StatusCode)
+	}
+
+	var rssErr *rss.StatusError
+	if errors.As(err, &rssErr) {
+		return transientStatus(rssErr.StatusCode)
+	}
+
+	return false
+}
+
+func transientStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/taiidani/no-time-to-explain/internal/bluesky"
+	"github.com/taiidani/no-time-to-explain/internal/mastodon"
+	"github.com/taiidani/no-time-to-explain/internal/rss"
+)
+
+func Test_isTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "network error",
+			err:  fmt.Errorf("wrapped: %w", &net.OpError{Op: "dial", Err: errors.New("connection refused")}),
+			want: true,
+		},
+		{
+			name: "cancelled",
+			err:  context.Canceled,
+			want: false,
+		},
+		{
+			name: "discord server error",
+			err:  &discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusBadGateway}},
+			want: true,
+		},
+		{
+			name: "discord rate limited",
+			err:  fmt.Errorf("could not relay feed entry: %w", &discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusTooManyRequests}}),
+			want: true,
+		},
+		{
+			name: "discord missing permissions",
+			err:  &discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusForbidden}},
+			want: false,
+		},
+		{
+			name: "bluesky rate limited",
+			err:  fmt.Errorf("bluesky feed error: %w", &bluesky.APIError{StatusCode: http.StatusTooManyRequests}),
+			want: true,
+		},
+		{
+			name: "bluesky unknown actor",
+			err:  &bluesky.APIError{StatusCode: http.StatusBadRequest},
+			want: false,
+		},
+		{
+			name: "mastodon unavailable",
+			err:  &mastodon.APIError{StatusCode: http.StatusServiceUnavailable},
+			want: true,
+		},
+		{
+			name: "rss not found",
+			err:  &rss.StatusError{StatusCode: http.StatusNotFound},
+			want: false,
+		},
+		{
+			name: "unknown error",
+			err:  errors.New("could not parse feed"),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransient(tt.err); got != tt.want {
+				t.Errorf("isTransient() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_retry(t *testing.T) {
+	transient := &bluesky.APIError{StatusCode: http.StatusInternalServerError}
+	permanent := &bluesky.APIError{StatusCode: http.StatusBadRequest}
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantErr   error
+		wantCalls int
+	}{
+		{
+			name:      "success",
+			errs:      []error{nil},
+			wantCalls: 1,
+		},
+		{
+			name:      "recovers",
+			errs:      []error{transient, transient, nil},
+			wantCalls: 3,
+		},
+		{
+			name:      "gives up",
+			errs:      []error{transient, transient, transient, nil},
+			wantErr:   transient,
+			wantCalls: 3,
+		},
+		{
+			name:      "permanent",
+			errs:      []error{permanent, nil},
+			wantErr:   permanent,
+			wantCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := retry(context.Background(), slog.Default(), 3, time.Millisecond, func() error {
+				calls++
+				return tt.errs[calls-1]
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("retry() error = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("retry() calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
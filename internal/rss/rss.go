@@ -0,0 +1,219 @@
+package rss
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type Client struct {
+	HttpClient *http.Client
+}
+
+// NewClient creates a new client for fetching RSS and Atom feeds
+func NewClient() *Client {
+	return &Client{
+		HttpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// StatusError is returned when the feed's server responds with an unsuccessful
+// status.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("feed error %d: %s", e.StatusCode, e.Body)
+}
+
+// Feed is the normalized form of an RSS or Atom document.
+type Feed struct {
+	Title string
+	Link  string
+	Items []Item
+}
+
+// Item is the normalized form of an RSS item or Atom entry.
+type Item struct {
+	ID        string
+	Title     string
+	Link      string
+	Summary   string
+	Published time.Time
+}
+
+// GetFeed fetches and parses the RSS or Atom feed at the given URL
+func (c *Client) GetFeed(url string) (*Feed, error) {
+	resp, err := c.HttpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+	}
+
+	return Parse(resp.Body)
+}
+
+// Parse reads an RSS 2.0 or Atom document.
+func Parse(r io.Reader) (*Feed, error) {
+	var doc struct {
+		XMLName xml.Name
+		rssDocument
+		atomDocument
+	}
+
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		// Most feeds are UTF-8; treat any other declared charset as such rather
+		// than failing outright.
+		return input, nil
+	}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("could not parse feed: %w", err)
+	}
+
+	switch doc.XMLName.Local {
+	case "rss":
+		return doc.rssDocument.feed(), nil
+	case "feed":
+		return doc.atomDocument.feed(), nil
+	}
+
+	return nil, fmt.Errorf("unsupported feed format %q", doc.XMLName.Local)
+}
+
+type rssDocument struct {
+	Channel struct {
+		Title string    `xml:"title"`
+		Link  string    `xml:"link"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type rssItem struct {
+	GUID        string `xml:"guid"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate"`
+}
+
+func (d *rssDocument) feed() *Feed {
+	ret := &Feed{
+		Title: strings.TrimSpace(d.Channel.Title),
+		Link:  strings.TrimSpace(d.Channel.Link),
+	}
+
+	for _, item := range d.Channel.Items {
+		id := strings.TrimSpace(item.GUID)
+		if id == "" {
+			id = strings.TrimSpace(item.Link)
+		}
+
+		ret.Items = append(ret.Items, Item{
+			ID:        id,
+			Title:     strings.TrimSpace(item.Title),
+			Link:      strings.TrimSpace(item.Link),
+			Summary:   strings.TrimSpace(item.Description),
+			Published: parseTime(item.PubDate),
+		})
+	}
+
+	return ret
+}
+
+type atomDocument struct {
+	Title   string      `xml:"title"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Links     []atomLink `xml:"link"`
+	Summary   string     `xml:"summary"`
+	Content   string     `xml:"content"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+func (d *atomDocument) feed() *Feed {
+	ret := &Feed{
+		Title: strings.TrimSpace(d.Title),
+		Link:  atomAlternateLink(d.Links),
+	}
+
+	for _, entry := range d.Entries {
+		summary := entry.Summary
+		if summary == "" {
+			summary = entry.Content
+		}
+
+		published := parseTime(entry.Published)
+		if published.IsZero() {
+			published = parseTime(entry.Updated)
+		}
+
+		ret.Items = append(ret.Items, Item{
+			ID:        strings.TrimSpace(entry.ID),
+			Title:     strings.TrimSpace(entry.Title),
+			Link:      atomAlternateLink(entry.Links),
+			Summary:   strings.TrimSpace(summary),
+			Published: published,
+		})
+	}
+
+	return ret
+}
+
+// atomAlternateLink returns the link pointing at the human-readable page.
+func atomAlternateLink(links []atomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+
+	return ""
+}
+
+// timeFormats are the layouts seen in the wild for RSS and Atom dates.
+var timeFormats = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC3339,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseTime parses the given feed date, returning the zero time if it could not
+// be understood.
+func parseTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, format := range timeFormats {
+		if tm, err := time.Parse(format, value); err == nil {
+			return tm
+		}
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,108 @@
+package rss
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_GetFeed(t *testing.T) {
+	srv := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		want    *Feed
+		wantErr bool
+	}{
+		{
+			name: "rss",
+			path: "/rss.xml",
+			want: &Feed{
+				Title: "Bungie News",
+				Link:  "https://www.bungie.net/7/en/News",
+				Items: []Item{
+					{
+						ID:        "d2-update-9-0-0-2",
+						Title:     "Destiny 2 Update 9.0.0.2",
+						Link:      "https://www.bungie.net/7/en/News/article/d2-update-9-0-0-2",
+						Summary:   "<p>Patch notes for <b>Update 9.0.0.2</b>.</p>",
+						Published: time.Date(2025, time.June, 10, 17, 0, 0, 0, time.FixedZone("", 0)),
+					},
+					{
+						ID:        "https://www.bungie.net/7/en/News/article/twid-06-05-2025",
+						Title:     "This Week in Destiny",
+						Link:      "https://www.bungie.net/7/en/News/article/twid-06-05-2025",
+						Summary:   "Here is what is happening this week.",
+						Published: time.Date(2025, time.June, 5, 17, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+		},
+		{
+			name: "atom",
+			path: "/atom.xml",
+			want: &Feed{
+				Title: "Patch Notes",
+				Link:  "https://example.com/",
+				Items: []Item{
+					{
+						ID:        "urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a",
+						Title:     "Hotfix 9.0.0.3",
+						Link:      "https://example.com/hotfix-9-0-0-3",
+						Summary:   "Fixes for the Exotic mission.",
+						Published: time.Date(2025, time.June, 12, 18, 30, 0, 0, time.UTC),
+					},
+					{
+						ID:        "urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6b",
+						Title:     "Known Issues",
+						Link:      "https://example.com/known-issues",
+						Summary:   "<p>A list of known issues.</p>",
+						Published: time.Date(2025, time.June, 11, 8, 0, 0, 0, time.FixedZone("", -4*60*60)),
+					},
+				},
+			},
+		},
+		{
+			name:    "not found",
+			path:    "/missing.xml",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient()
+			got, err := c.GetFeed(srv.URL + tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetFeed() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got.Title != tt.want.Title || got.Link != tt.want.Link || len(got.Items) != len(tt.want.Items) {
+				t.Fatalf("GetFeed() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got.Items {
+				if !got.Items[i].Published.Equal(tt.want.Items[i].Published) {
+					t.Errorf("Items[%d].Published = %v, want %v", i, got.Items[i].Published, tt.want.Items[i].Published)
+				}
+				got.Items[i].Published = tt.want.Items[i].Published
+				if !reflect.DeepEqual(got.Items[i], tt.want.Items[i]) {
+					t.Errorf("Items[%d] = %+v, want %+v", i, got.Items[i], tt.want.Items[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParse_unsupported(t *testing.T) {
+	_, err := Parse(strings.NewReader(`<html><body>Not a feed</body></html>`))
+	if err == nil {
+		t.Error("Parse() expected an error for a non-feed document")
+	}
+}
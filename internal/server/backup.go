@@ -0,0 +1,71 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/taiidani/no-time-to-explain/internal/config"
+)
+
+// maxImportSize limits the size of uploaded configuration documents.
+const maxImportSize = 10 << 20
+
+// exportHandler downloads every message and feed as a configuration document.
+func (s *Server) exportHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.FormValue("format")
+	if format == "" {
+		format = config.FormatYAML
+	}
+	if format != config.FormatYAML && format != config.FormatJSON {
+		errorResponse(r.Context(), w, http.StatusBadRequest, fmt.Errorf("unknown format %q", format))
+		return
+	}
+
+	doc, err := config.Export(r.Context(), s.queries)
+	if err != nil {
+		errorResponse(r.Context(), w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/"+format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"no-time-to-explain.%s\"", format))
+	if err := config.Encode(w, doc, format); err != nil {
+		errorResponse(r.Context(), w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+type importBag struct {
+	DryRun bool
+	Error  error
+	config.Diff
+}
+
+// importHandler upserts an uploaded configuration document, rendering the
+// changes that it made. Dry runs render the changes without making them.
+func (s *Server) importHandler(w http.ResponseWriter, r *http.Request) {
+	bag := importBag{DryRun: r.FormValue("mode") != "upsert"}
+	template := "fragment_import.gohtml"
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		errorResponse(r.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+	defer file.Close()
+
+	doc, err := config.Decode(file)
+	if err != nil {
+		bag.Error = err
+		renderHtml(w, http.StatusOK, template, bag)
+		return
+	}
+
+	bag.Diff, bag.Error = config.Import(r.Context(), s.conn, doc, bag.DryRun)
+	if bag.Error == nil && !bag.DryRun {
+		s.triggers.Invalidate()
+	}
+
+	renderHtml(w, http.StatusOK, template, bag)
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"log/slog"
 	"net/http"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -20,11 +21,17 @@ const (
 func (s *Server) indexHandler(w http.ResponseWriter, r *http.Request) {
 	type indexBag struct {
 		baseBag
+		Guilds        []string
 		Channels      []*discordgo.Channel
 		MessageGroups map[string][]models.Message
-		Bluesky       struct {
-			Feeds []models.Feed
-		}
+		Feeds         []models.Feed
+		Users         []discordgo.User
+
+		// GroupHits are the total hits of each trigger's responses
+		GroupHits map[string]int32
+
+		// Sparklines chart the recent daily hits of each message
+		Sparklines map[int32]string
 	}
 
 	bag := indexBag{baseBag: s.newBag(r)}
@@ -47,6 +54,20 @@ func (s *Server) indexHandler(w http.ResponseWriter, r *http.Request) {
 		// }
 	}
 
+	// Chart how often each message has fired recently
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -(sparklineDays - 1))
+	hits, err := s.queries.LoadMessageHits(r.Context(), since)
+	if err != nil {
+		errorResponse(r.Context(), w, http.StatusInternalServerError, err)
+		return
+	}
+	bag.Sparklines = messageSparklines(messages, hits, since)
+
+	bag.GroupHits = map[string]int32{}
+	for _, msg := range messages {
+		bag.GroupHits[msg.Trigger] += msg.HitCount
+	}
+
 	// Sort groups by trigger for consistent display
 	// sort.Slice(bag.MessageGroups, func(i, j int) bool {
 	// 	if bag.MessageGroups[i].Trigger != bag.MessageGroups[j].Trigger {
@@ -61,28 +82,67 @@ func (s *Server) indexHandler(w http.ResponseWriter, r *http.Request) {
 		errorResponse(r.Context(), w, http.StatusInternalServerError, err)
 		return
 	}
-	bag.Bluesky.Feeds = feeds
+	bag.Feeds = feeds
 
-	// Load all channels in Unknown Space, fall back upon internal testing
-	for _, guildID := range []string{unknownSpaceServerID, taiidaniTestingServerID} {
-		channels, err := s.discord.GuildChannels(guildID, discordgo.WithContext(r.Context()))
-		if err != nil {
-			slog.Warn("Skipping guild", "id", guildID, "err", err.Error())
-			continue
-		}
-		bag.Channels = append(bag.Channels, channels...)
-	}
+	bag.Channels = s.loadChannels(r)
+	bag.Guilds = guildIDs(bag.Channels)
 
-	sort.Slice(bag.Channels, func(i, j int) bool {
-		left := strings.ToLower(bag.Channels[i].Name)
-		right := strings.ToLower(bag.Channels[j].Name)
-		return left < right
-	})
+	bag.Users, err = s.loadRecentSenders(r)
+	if err != nil {
+		slog.Warn("Could not load recent senders", "err", err)
+	}
 
 	template := "index.gohtml"
 	renderHtml(w, http.StatusOK, template, bag)
 }
 
+// sparklineDays is the number of days charted by each message's sparkline.
+const sparklineDays = 14
+
+// sparklineBars are the characters that a sparkline is drawn with, from least
+// to most hits.
+var sparklineBars = []rune("▁▂▃▄▅▆▇█")
+
+// messageSparklines charts the daily hits of each message since the given day.
+func messageSparklines(messages []models.Message, hits []models.MessageHit, since time.Time) map[int32]string {
+	daily := map[int32][]int32{}
+	for _, msg := range messages {
+		daily[msg.ID] = make([]int32, sparklineDays)
+	}
+
+	for _, hit := range hits {
+		day := int(hit.Day.Sub(since) / (24 * time.Hour))
+		if counts, found := daily[hit.MessageID]; found && day >= 0 && day < len(counts) {
+			counts[day] += hit.Hits
+		}
+	}
+
+	ret := map[int32]string{}
+	for id, counts := range daily {
+		ret[id] = sparkline(counts)
+	}
+	return ret
+}
+
+// sparkline draws the counts as a row of bars scaled to the largest count.
+// Zero is always drawn as the shortest bar.
+func sparkline(counts []int32) string {
+	highest := int32(0)
+	for _, count := range counts {
+		highest = max(highest, count)
+	}
+
+	ret := make([]rune, 0, len(counts))
+	for _, count := range counts {
+		bar := 0
+		if highest > 0 && count > 0 {
+			bar = 1 + int(count)*(len(sparklineBars)-2)/int(highest)
+		}
+		ret = append(ret, sparklineBars[bar])
+	}
+	return string(ret)
+}
+
 func (s *Server) channelsHandler(w http.ResponseWriter, r *http.Request) {
 	type indexBag struct {
 		baseBag
@@ -91,6 +151,17 @@ func (s *Server) channelsHandler(w http.ResponseWriter, r *http.Request) {
 
 	bag := indexBag{baseBag: s.newBag(r)}
 
+	bag.Channels = s.loadChannels(r)
+
+	template := "channels.gohtml"
+	renderHtml(w, http.StatusOK, template, bag)
+}
+
+// loadChannels returns all channels in Unknown Space and the internal testing
+// server, sorted by name.
+func (s *Server) loadChannels(r *http.Request) []*discordgo.Channel {
+	ret := []*discordgo.Channel{}
+
 	// Load all channels in Unknown Space, fall back upon internal testing
 	for _, guildID := range []string{unknownSpaceServerID, taiidaniTestingServerID} {
 		channels, err := s.discord.GuildChannels(guildID, discordgo.WithContext(r.Context()))
@@ -98,17 +169,27 @@ func (s *Server) channelsHandler(w http.ResponseWriter, r *http.Request) {
 			slog.Warn("Skipping guild", "id", guildID, "err", err.Error())
 			continue
 		}
-		bag.Channels = append(bag.Channels, channels...)
+		ret = append(ret, channels...)
 	}
 
-	sort.Slice(bag.Channels, func(i, j int) bool {
-		left := strings.ToLower(bag.Channels[i].Name)
-		right := strings.ToLower(bag.Channels[j].Name)
+	sort.Slice(ret, func(i, j int) bool {
+		left := strings.ToLower(ret[i].Name)
+		right := strings.ToLower(ret[j].Name)
 		return left < right
 	})
 
-	template := "channels.gohtml"
-	renderHtml(w, http.StatusOK, template, bag)
+	return ret
+}
+
+// guildIDs returns the distinct guilds of the given channels.
+func guildIDs(channels []*discordgo.Channel) []string {
+	ret := []string{}
+	for _, channel := range channels {
+		if !slices.Contains(ret, channel.GuildID) {
+			ret = append(ret, channel.GuildID)
+		}
+	}
+	return ret
 }
 
 func (s *Server) usersHandler(w http.ResponseWriter, r *http.Request) {
@@ -120,11 +201,26 @@ func (s *Server) usersHandler(w http.ResponseWriter, r *http.Request) {
 	bag := indexBag{baseBag: s.newBag(r)}
 
 	// Load all recent senders from the cache
-	userKeys, err := s.backend.Keys(r.Context(), "recent-senders:*")
+	users, err := s.loadRecentSenders(r)
 	if err != nil {
 		errorResponse(r.Context(), w, http.StatusInternalServerError, err)
 		return
 	}
+	bag.Users = users
+
+	template := "users.gohtml"
+	renderHtml(w, http.StatusOK, template, bag)
+}
+
+// loadRecentSenders returns the users that the bot has seen sending messages in
+// the last week, sorted by username.
+func (s *Server) loadRecentSenders(r *http.Request) ([]discordgo.User, error) {
+	ret := []discordgo.User{}
+
+	userKeys, err := s.backend.Keys(r.Context(), "recent-senders:*")
+	if err != nil {
+		return nil, err
+	}
 
 	for _, key := range userKeys {
 		key = strings.TrimPrefix(key, "no-time-to-explain:")
@@ -132,32 +228,68 @@ func (s *Server) usersHandler(w http.ResponseWriter, r *http.Request) {
 		var user discordgo.User
 		err = s.backend.Get(r.Context(), key, &user)
 		if err != nil {
-			errorResponse(r.Context(), w, http.StatusInternalServerError, err)
-			return
+			return nil, err
 		}
 
-		bag.Users = append(bag.Users, user)
+		ret = append(ret, user)
 	}
 
-	sort.Slice(bag.Users, func(i, j int) bool {
-		left := strings.ToLower(bag.Users[i].Username)
-		right := strings.ToLower(bag.Users[j].Username)
+	sort.Slice(ret, func(i, j int) bool {
+		left := strings.ToLower(ret[i].Username)
+		right := strings.ToLower(ret[j].Username)
 		return left < right
 	})
 
-	template := "users.gohtml"
-	renderHtml(w, http.StatusOK, template, bag)
+	return ret, nil
+}
+
+// guildRole is a role along with the guild that it belongs to.
+type guildRole struct {
+	*discordgo.Role
+	GuildID string
+}
+
+// loadRoles returns all roles in Unknown Space and the internal testing server,
+// sorted by name.
+func (s *Server) loadRoles(r *http.Request) []guildRole {
+	ret := []guildRole{}
+
+	for _, guildID := range []string{unknownSpaceServerID, taiidaniTestingServerID} {
+		roles, err := s.discord.GuildRoles(guildID, discordgo.WithContext(r.Context()))
+		if err != nil {
+			slog.Warn("Skipping guild", "id", guildID, "err", err.Error())
+			continue
+		}
+
+		for _, role := range roles {
+			// The @everyone role shares the guild's ID and is held by everyone
+			if role.ID == guildID {
+				continue
+			}
+			ret = append(ret, guildRole{Role: role, GuildID: guildID})
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		left := strings.ToLower(ret[i].Name)
+		right := strings.ToLower(ret[j].Name)
+		return left < right
+	})
+
+	return ret
 }
 
 func (s *Server) feedAddHandler(w http.ResponseWriter, r *http.Request) {
 	newFeed := models.Feed{
-		Source:      "bluesky",
+		Source:      r.FormValue("source"),
 		Author:      r.FormValue("author"),
+		ChannelID:   r.FormValue("channel"),
+		RichEmbed:   r.FormValue("rich_embed") == "enabled",
 		LastMessage: time.Now(),
 	}
 
 	// Validate inputs
-	if err := s.queries.ValidateFeed(newFeed); err != nil {
+	if err := s.queries.ValidateFeed(&newFeed); err != nil {
 		errorResponse(r.Context(), w, http.StatusInternalServerError, err)
 		return
 	}
@@ -166,8 +298,87 @@ func (s *Server) feedAddHandler(w http.ResponseWriter, r *http.Request) {
 	_, err := s.queries.CreateFeed(r.Context(), models.CreateFeedParams{
 		Source:         newFeed.Source,
 		Author:         newFeed.Author,
-		AuthorSourceID: "",
+		AuthorSourceID: newFeed.AuthorSourceID,
 		LastMessage:    newFeed.LastMessage,
+		ChannelID:      newFeed.ChannelID,
+		RichEmbed:      newFeed.RichEmbed,
+	})
+	if err != nil {
+		errorResponse(r.Context(), w, http.StatusInternalServerError, err)
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+func (s *Server) feedGetHandler(w http.ResponseWriter, r *http.Request) {
+	type feedBag struct {
+		models.Feed
+		Channels []*discordgo.Channel
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		errorResponse(r.Context(), w, http.StatusInternalServerError, err)
+		return
+	}
+
+	feed, err := s.queries.GetFeed(r.Context(), int32(id))
+	if err != nil {
+		errorResponse(r.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+
+	bag := feedBag{Feed: feed, Channels: s.loadChannels(r)}
+
+	template := "fragment_feed.gohtml"
+	renderHtml(w, http.StatusOK, template, bag)
+}
+
+func (s *Server) feedEditHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 32)
+	if err != nil {
+		errorResponse(r.Context(), w, http.StatusInternalServerError, err)
+		return
+	}
+
+	feed, err := s.queries.GetFeed(r.Context(), int32(id))
+	if err != nil {
+		errorResponse(r.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+
+	feed.ChannelID = r.FormValue("channel")
+	feed.RichEmbed = r.FormValue("rich_embed") == "enabled"
+	feed.IncludeReplies = r.FormValue("include_replies") == "enabled"
+	feed.IncludeQuotes = r.FormValue("include_quotes") == "enabled"
+	feed.IncludeReposts = r.FormValue("include_reposts") == "enabled"
+	feed.MediaOnly = r.FormValue("media_only") == "enabled"
+	feed.KeywordAllow = r.FormValue("keyword_allow")
+	feed.KeywordBlock = r.FormValue("keyword_block")
+	feed.Mirror = r.FormValue("mirror")
+
+	// Validate inputs
+	if err := s.queries.ValidateFeed(&feed); err != nil {
+		errorResponse(r.Context(), w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Save the Feed, leaving its last message to the refreshes that advance it
+	_, err = s.queries.UpdateFeedSettings(r.Context(), models.UpdateFeedSettingsParams{
+		ID:             feed.ID,
+		Source:         feed.Source,
+		Author:         feed.Author,
+		AuthorSourceID: feed.AuthorSourceID,
+		ChannelID:      feed.ChannelID,
+		RichEmbed:      feed.RichEmbed,
+		IncludeReplies: feed.IncludeReplies,
+		IncludeQuotes:  feed.IncludeQuotes,
+		IncludeReposts: feed.IncludeReposts,
+		MediaOnly:      feed.MediaOnly,
+		KeywordAllow:   feed.KeywordAllow,
+		KeywordBlock:   feed.KeywordBlock,
+		Mirror:         feed.Mirror,
 	})
 	if err != nil {
 		errorResponse(r.Context(), w, http.StatusInternalServerError, err)
@@ -1,14 +1,31 @@
 package server
 
 import (
+	"database/sql"
+	"log/slog"
 	"net/http"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/taiidani/no-time-to-explain/internal/authz"
 	"github.com/taiidani/no-time-to-explain/internal/db/models"
+	"github.com/taiidani/no-time-to-explain/internal/response"
 )
 
 func (s *Server) messageGetHandler(w http.ResponseWriter, r *http.Request) {
+	type messageBag struct {
+		models.Message
+		Guilds   []string
+		Channels []*discordgo.Channel
+		Weekdays []time.Weekday
+		Users    []discordgo.User
+		Roles    []guildRole
+	}
+
 	id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
 	if err != nil {
 		errorResponse(r.Context(), w, http.StatusInternalServerError, err)
@@ -21,21 +38,42 @@ func (s *Server) messageGetHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	bag := messageBag{Message: message, Channels: s.loadChannels(r)}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		bag.Weekdays = append(bag.Weekdays, day)
+	}
+	bag.Guilds = guildIDs(bag.Channels)
+
+	bag.Users, err = s.loadRecentSenders(r)
+	if err != nil {
+		slog.Warn("Could not load recent senders", "err", err)
+	}
+
+	// Keep targets that are no longer recent senders selectable, so that saving
+	// the form does not drop them
+	for _, id := range message.TargetUserIDs() {
+		if !slices.ContainsFunc(bag.Users, func(user discordgo.User) bool { return user.ID == id }) {
+			bag.Users = append(bag.Users, discordgo.User{ID: id, Username: id})
+		}
+	}
+
+	bag.Roles = s.loadRoles(r)
+	for _, id := range message.TargetRoleIDs() {
+		if !slices.ContainsFunc(bag.Roles, func(role guildRole) bool { return role.ID == id }) {
+			bag.Roles = append(bag.Roles, guildRole{Role: &discordgo.Role{ID: id, Name: id}})
+		}
+	}
+
 	template := "fragment_message.gohtml"
-	renderHtml(w, http.StatusOK, template, message)
+	renderHtml(w, http.StatusOK, template, bag)
 }
 
 func (s *Server) messageAddHandler(w http.ResponseWriter, r *http.Request) {
-	newMessage := models.Message{
-		Enabled:  r.FormValue("enabled") == "enabled",
-		Sender:   r.FormValue("sender"),
-		Trigger:  r.FormValue("trigger"),
-		Response: r.FormValue("response"),
-	}
+	newMessage := messageFromForm(r)
 
 	// Validate inputs
 	if err := s.queries.ValidateMessage(newMessage); err != nil {
-		errorResponse(r.Context(), w, http.StatusInternalServerError, err)
+		errorResponse(r.Context(), w, http.StatusBadRequest, err)
 		return
 	}
 
@@ -45,11 +83,39 @@ func (s *Server) messageAddHandler(w http.ResponseWriter, r *http.Request) {
 		Sender:   newMessage.Sender,
 		Trigger:  newMessage.Trigger,
 		Response: newMessage.Response,
+
+		GuildAllow:   newMessage.GuildAllow,
+		GuildBlock:   newMessage.GuildBlock,
+		ChannelAllow: newMessage.ChannelAllow,
+		ChannelBlock: newMessage.ChannelBlock,
+
+		ChannelCooldown: newMessage.ChannelCooldown,
+		UserCooldown:    newMessage.UserCooldown,
+		RateLimit:       newMessage.RateLimit,
+
+		Weight:          newMessage.Weight,
+		ActiveFrom:      newMessage.ActiveFrom,
+		ActiveUntil:     newMessage.ActiveUntil,
+		ActiveDays:      newMessage.ActiveDays,
+		ActiveHourStart: newMessage.ActiveHourStart,
+		ActiveHourEnd:   newMessage.ActiveHourEnd,
+		Timezone:        newMessage.Timezone,
+
+		Action:     newMessage.Action,
+		EmbedTitle: newMessage.EmbedTitle,
+		EmbedColor: newMessage.EmbedColor,
+		EmbedImage: newMessage.EmbedImage,
+		ThreadName: newMessage.ThreadName,
+
+		TargetUsers: newMessage.TargetUsers,
+		TargetRoles: newMessage.TargetRoles,
+		RoleMatch:   newMessage.RoleMatch,
 	})
 	if err != nil {
 		errorResponse(r.Context(), w, http.StatusInternalServerError, err)
 		return
 	}
+	s.triggers.Invalidate()
 
 	http.Redirect(w, r, "/", http.StatusFound)
 }
@@ -61,17 +127,12 @@ func (s *Server) messageEditHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	newMessage := models.Message{
-		ID:       int32(id),
-		Enabled:  r.FormValue("enabled") == "enabled",
-		Sender:   r.FormValue("sender"),
-		Trigger:  r.FormValue("trigger"),
-		Response: r.FormValue("response"),
-	}
+	newMessage := messageFromForm(r)
+	newMessage.ID = int32(id)
 
 	// Validate inputs
 	if err := s.queries.ValidateMessage(newMessage); err != nil {
-		errorResponse(r.Context(), w, http.StatusInternalServerError, err)
+		errorResponse(r.Context(), w, http.StatusBadRequest, err)
 		return
 	}
 
@@ -82,11 +143,39 @@ func (s *Server) messageEditHandler(w http.ResponseWriter, r *http.Request) {
 		Sender:   newMessage.Sender,
 		Trigger:  newMessage.Trigger,
 		Response: newMessage.Response,
+
+		GuildAllow:   newMessage.GuildAllow,
+		GuildBlock:   newMessage.GuildBlock,
+		ChannelAllow: newMessage.ChannelAllow,
+		ChannelBlock: newMessage.ChannelBlock,
+
+		ChannelCooldown: newMessage.ChannelCooldown,
+		UserCooldown:    newMessage.UserCooldown,
+		RateLimit:       newMessage.RateLimit,
+
+		Weight:          newMessage.Weight,
+		ActiveFrom:      newMessage.ActiveFrom,
+		ActiveUntil:     newMessage.ActiveUntil,
+		ActiveDays:      newMessage.ActiveDays,
+		ActiveHourStart: newMessage.ActiveHourStart,
+		ActiveHourEnd:   newMessage.ActiveHourEnd,
+		Timezone:        newMessage.Timezone,
+
+		Action:     newMessage.Action,
+		EmbedTitle: newMessage.EmbedTitle,
+		EmbedColor: newMessage.EmbedColor,
+		EmbedImage: newMessage.EmbedImage,
+		ThreadName: newMessage.ThreadName,
+
+		TargetUsers: newMessage.TargetUsers,
+		TargetRoles: newMessage.TargetRoles,
+		RoleMatch:   newMessage.RoleMatch,
 	})
 	if err != nil {
 		errorResponse(r.Context(), w, http.StatusInternalServerError, err)
 		return
 	}
+	s.triggers.Invalidate()
 
 	http.Redirect(w, r, "/", http.StatusFound)
 }
@@ -103,10 +192,145 @@ func (s *Server) messageDeleteHandler(w http.ResponseWriter, r *http.Request) {
 		errorResponse(r.Context(), w, http.StatusInternalServerError, err)
 		return
 	}
+	s.triggers.Invalidate()
 
 	http.Redirect(w, r, "/", http.StatusFound)
 }
 
+// messageFromForm reads the fields of the message form.
+func messageFromForm(r *http.Request) models.Message {
+	return models.Message{
+		Enabled:  r.FormValue("enabled") == "enabled",
+		Sender:   r.FormValue("sender"),
+		Trigger:  r.FormValue("trigger"),
+		Response: r.FormValue("response"),
+
+		GuildAllow:   formIDs(r, "guild_allow"),
+		GuildBlock:   formIDs(r, "guild_block"),
+		ChannelAllow: formIDs(r, "channel_allow"),
+		ChannelBlock: formIDs(r, "channel_block"),
+
+		ChannelCooldown: formInt(r, "channel_cooldown", 0),
+		UserCooldown:    formInt(r, "user_cooldown", 0),
+		RateLimit:       formInt(r, "rate_limit", 0),
+
+		Weight:          formInt(r, "weight", 1),
+		ActiveFrom:      formDate(r, "active_from"),
+		ActiveUntil:     formDate(r, "active_until"),
+		ActiveDays:      formIDs(r, "active_days"),
+		ActiveHourStart: formInt(r, "active_hour_start", 0),
+		ActiveHourEnd:   formInt(r, "active_hour_end", 0),
+		Timezone:        strings.TrimSpace(r.FormValue("timezone")),
+
+		Action:     r.FormValue("action"),
+		EmbedTitle: strings.TrimSpace(r.FormValue("embed_title")),
+		EmbedColor: formColor(r, "embed_color"),
+		EmbedImage: strings.TrimSpace(r.FormValue("embed_image")),
+		ThreadName: strings.TrimSpace(r.FormValue("thread_name")),
+
+		TargetUsers: formIDs(r, "target_users"),
+		TargetRoles: formIDs(r, "target_roles"),
+		RoleMatch:   r.FormValue("role_match"),
+	}
+}
+
+// formIDs returns the guild or channel IDs selected in a multi-select input,
+// formatted for storage.
+func formIDs(r *http.Request, name string) string {
+	_ = r.ParseForm()
+	return models.JoinIDs(r.Form[name])
+}
+
+// formInt returns the number entered in the given input, or the fallback if it
+// is blank. Anything that is not a number is treated as blank.
+func formInt(r *http.Request, name string, fallback int32) int32 {
+	ret, err := strconv.ParseInt(r.FormValue(name), 10, 32)
+	if err != nil {
+		return fallback
+	}
+	return int32(ret)
+}
+
+// formDate returns the date entered in the given date input, if any.
+func formDate(r *http.Request, name string) sql.NullTime {
+	ret, err := time.Parse(time.DateOnly, r.FormValue(name))
+	if err != nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: ret, Valid: true}
+}
+
+// formColor returns the color chosen in the given color input, such as
+// "#6364ff", or zero if it is blank.
+func formColor(r *http.Request, name string) int32 {
+	ret, err := strconv.ParseInt(strings.TrimPrefix(r.FormValue(name), "#"), 16, 32)
+	if err != nil {
+		return 0
+	}
+	return int32(ret)
+}
+
+// messageActionHandler renders the fields of the message form that apply to
+// the chosen action, whenever the action is changed.
+func (s *Server) messageActionHandler(w http.ResponseWriter, r *http.Request) {
+	template := "fragment_message_action.gohtml"
+	renderHtml(w, http.StatusOK, template, messageFromForm(r))
+}
+
+type messagePreviewBag struct {
+	Error         error
+	Matched       bool
+	Match         string
+	Groups        []string
+	ResponseError error
+	Response      string
+}
+
+// messagePreviewHandler tests a trigger against sample text, reporting whether
+// it compiles and what it matches, and renders the response as it would be sent.
+func (s *Server) messagePreviewHandler(w http.ResponseWriter, r *http.Request) {
+	bag := messagePreviewBag{}
+
+	re, err := regexp.Compile(r.FormValue("trigger"))
+	if err != nil {
+		bag.Error = err
+	}
+
+	var match []string
+	if re != nil {
+		match = re.FindStringSubmatch(r.FormValue("sample"))
+	}
+	if match != nil {
+		bag.Matched = true
+		bag.Match = match[0]
+		bag.Groups = match[1:]
+	}
+
+	tpl, err := response.Parse(r.FormValue("response"))
+	if err == nil && re != nil {
+		err = tpl.Validate(re)
+	}
+	if err != nil {
+		bag.ResponseError = err
+	} else if re != nil {
+		vars := response.Vars{
+			Name:       "you",
+			Channel:    "general",
+			Now:        time.Now(),
+			Groups:     match,
+			GroupNames: re.SubexpNames(),
+		}
+		if sess, ok := r.Context().Value(sessionKey).(authz.Session); ok && sess.DiscordUser != nil {
+			vars.SenderID = sess.DiscordUser.ID
+			vars.Name = sess.DiscordUser.Username
+		}
+		bag.Response = tpl.Render(vars)
+	}
+
+	template := "fragment_message_preview.gohtml"
+	renderHtml(w, http.StatusOK, template, bag)
+}
+
 func (s *Server) messageSendHandler(w http.ResponseWriter, r *http.Request) {
 	channelID := r.FormValue("channel")
 	message := r.FormValue("message")
@@ -10,12 +10,14 @@ import (
 	"net/http"
 	"os"
 	"regexp"
+	"slices"
 
 	"github.com/bwmarrin/discordgo"
 	libauthz "github.com/taiidani/go-lib/authz"
 	"github.com/taiidani/go-lib/cache"
 	"github.com/taiidani/no-time-to-explain/internal/authz"
 	"github.com/taiidani/no-time-to-explain/internal/db/models"
+	"github.com/taiidani/no-time-to-explain/internal/triggers"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
@@ -25,7 +27,9 @@ type Server struct {
 	discord        *discordgo.Session
 	publicURL      string
 	port           string
+	conn           *sql.DB
 	queries        *models.Queries
+	triggers       *triggers.Index
 	*http.Server
 }
 
@@ -35,7 +39,7 @@ var templates embed.FS
 // DevMode can be toggled to pull rendered files from the filesystem or the embedded FS.
 var DevMode = os.Getenv("DEV") == "true"
 
-func NewServer(conn *sql.DB, backend cache.Cache, b *discordgo.Session, port string) *Server {
+func NewServer(conn *sql.DB, backend cache.Cache, b *discordgo.Session, index *triggers.Index, port string) *Server {
 	mux := http.NewServeMux()
 
 	publicURL := os.Getenv("PUBLIC_URL")
@@ -56,7 +60,9 @@ func NewServer(conn *sql.DB, backend cache.Cache, b *discordgo.Session, port str
 		backend:        backend,
 		discord:        b,
 		sessionManager: sess,
+		conn:           conn,
 		queries:        models.New(conn),
+		triggers:       index,
 	}
 	srv.addRoutes(mux)
 
@@ -79,19 +85,26 @@ func (s *Server) addRoutes(mux *http.ServeMux) {
 	handle("GET /login", http.HandlerFunc(s.login))
 	handle("GET /logout", http.HandlerFunc(s.logout))
 	handle("POST /feed/add", s.sessionMiddleware(http.HandlerFunc(s.feedAddHandler)))
+	handle("POST /feed/edit", s.sessionMiddleware(http.HandlerFunc(s.feedEditHandler)))
 	handle("POST /feed/delete", s.sessionMiddleware(http.HandlerFunc(s.feedDeleteHandler)))
+	handle("GET /feed/{id}", s.sessionMiddleware(http.HandlerFunc(s.feedGetHandler)))
 	handle("POST /message/add", s.sessionMiddleware(http.HandlerFunc(s.messageAddHandler)))
 	handle("POST /message/edit", s.sessionMiddleware(http.HandlerFunc(s.messageEditHandler)))
 	handle("POST /message/delete", s.sessionMiddleware(http.HandlerFunc(s.messageDeleteHandler)))
+	handle("POST /message/preview", s.sessionMiddleware(http.HandlerFunc(s.messagePreviewHandler)))
+	handle("POST /message/action", s.sessionMiddleware(http.HandlerFunc(s.messageActionHandler)))
 	handle("POST /message/send", s.sessionMiddleware(http.HandlerFunc(s.messageSendHandler)))
 	handle("GET /message/{id}", s.sessionMiddleware(http.HandlerFunc(s.messageGetHandler)))
+	handle("GET /export", s.sessionMiddleware(http.HandlerFunc(s.exportHandler)))
+	handle("POST /import", s.sessionMiddleware(http.HandlerFunc(s.importHandler)))
 	handle("/assets/", http.HandlerFunc(s.assetsHandler))
 	handle("/", http.HandlerFunc(s.errorNotFoundHandler))
 }
 
 // templateFuncs are made available to every template rendered by renderHtml.
 var templateFuncs = template.FuncMap{
-	"linkify": linkify,
+	"linkify":  linkify,
+	"contains": slices.Contains[[]string],
 }
 
 // urlPattern matches bare URLs so they can be rendered as clickable links.
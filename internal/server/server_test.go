@@ -1,7 +1,14 @@
 package server
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/taiidani/no-time-to-explain/internal/db/models"
 )
 
 func Test_linkify(t *testing.T) {
@@ -61,3 +68,160 @@ func Test_linkify(t *testing.T) {
 		})
 	}
 }
+
+func Test_messagePreviewHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		trigger  string
+		sample   string
+		response string
+		want     []string
+	}{
+		{
+			name:    "match with groups",
+			trigger: `(?i)hello (\w+)`,
+			sample:  "Oh, hello there",
+			want:    []string{"Matches <code>hello there</code>", "<code>there</code>"},
+		},
+		{
+			name:    "no match",
+			trigger: `^ping$`,
+			sample:  "pong",
+			want:    []string{"Does not match"},
+		},
+		{
+			name:    "invalid trigger",
+			trigger: `invalid[`,
+			sample:  "invalid[",
+			want:    []string{"Invalid trigger", "missing closing ]"},
+		},
+		{
+			name:     "rendered response",
+			trigger:  `^is (\w+) down`,
+			sample:   "is bungie down",
+			response: "{name}, {1} is fine",
+			want:     []string{"Responds with: you, bungie is fine"},
+		},
+		{
+			name:     "invalid response",
+			trigger:  `^ping$`,
+			sample:   "ping",
+			response: "{1}",
+			want:     []string{"Invalid response", "capture group"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{"trigger": {tt.trigger}, "sample": {tt.sample}, "response": {tt.response}}
+			r := httptest.NewRequest(http.MethodPost, "/message/preview", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+
+			(&Server{}).messagePreviewHandler(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("code = %d, want %d", w.Code, http.StatusOK)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(w.Body.String(), want) {
+					t.Errorf("body = %q, want it to contain %q", w.Body.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func Test_messageActionHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		form    url.Values
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "text",
+			form:    url.Values{"action": {""}},
+			want:    []string{"replies to the trigger"},
+			notWant: []string{"embed_title", "thread_name"},
+		},
+		{
+			name: "embed",
+			form: url.Values{"action": {"embed"}, "embed_title": {"Bees"}, "embed_color": {"#6364ff"}},
+			want: []string{`name="embed_title"`, `value="Bees"`, `value="#6364ff"`},
+		},
+		{
+			name:    "thread",
+			form:    url.Values{"action": {"thread"}, "embed_title": {"Bees"}},
+			want:    []string{`name="thread_name"`},
+			notWant: []string{"embed_title"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/message/action", strings.NewReader(tt.form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+
+			(&Server{}).messageActionHandler(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("code = %d, want %d", w.Code, http.StatusOK)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(w.Body.String(), want) {
+					t.Errorf("body = %q, want it to contain %q", w.Body.String(), want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(w.Body.String(), notWant) {
+					t.Errorf("body = %q, want it not to contain %q", w.Body.String(), notWant)
+				}
+			}
+		})
+	}
+}
+
+func Test_sparkline(t *testing.T) {
+	tests := []struct {
+		name   string
+		counts []int32
+		want   string
+	}{
+		{name: "empty", counts: []int32{}, want: ""},
+		{name: "never fired", counts: []int32{0, 0, 0}, want: "▁▁▁"},
+		{name: "single hit", counts: []int32{0, 1, 0}, want: "▁█▁"},
+		{name: "scaled", counts: []int32{0, 1, 3, 6}, want: "▁▃▅█"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sparkline(tt.counts); got != tt.want {
+				t.Errorf("sparkline() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_messageSparklines(t *testing.T) {
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	messages := []models.Message{{ID: 1}, {ID: 2}}
+	hits := []models.MessageHit{
+		{MessageID: 1, Day: since, Hits: 2},
+		{MessageID: 1, Day: since.AddDate(0, 0, sparklineDays-1), Hits: 4},
+		{MessageID: 1, Day: since.AddDate(0, 0, sparklineDays), Hits: 100},
+		{MessageID: 3, Day: since, Hits: 1},
+	}
+
+	got := messageSparklines(messages, hits, since)
+
+	if want := "▅" + strings.Repeat("▁", sparklineDays-2) + "█"; got[1] != want {
+		t.Errorf("messageSparklines()[1] = %q, want %q", got[1], want)
+	}
+	if want := strings.Repeat("▁", sparklineDays); got[2] != want {
+		t.Errorf("messageSparklines()[2] = %q, want %q", got[2], want)
+	}
+	if _, found := got[3]; found {
+		t.Errorf("messageSparklines() charted unknown message 3")
+	}
+}
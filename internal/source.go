@@ -0,0 +1,285 @@
+package internal
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/taiidani/no-time-to-explain/internal/bluesky"
+	"github.com/taiidani/no-time-to-explain/internal/db/models"
+	"github.com/taiidani/no-time-to-explain/internal/mastodon"
+	"github.com/taiidani/no-time-to-explain/internal/rss"
+)
+
+// feedSource is implemented for each supported `models.Feed.Source`, providing
+// the entries that are due to be relayed to the feed's Discord channel.
+type feedSource interface {
+	// Entries returns the feed's entries that were published after its
+	// LastMessage and pass its content rules, newest first.
+	Entries(ctx context.Context, logger *slog.Logger, feed models.Feed) ([]feedEntry, error)
+}
+
+// feedEntry is a single item from a feed, ready to be relayed to Discord.
+type feedEntry struct {
+	// ID uniquely identifies the entry within its feed, such as a post URI.
+	ID string
+
+	// CID identifies the version of the entry, where the source provides one.
+	CID string
+
+	// PublishedAt is compared against the feed's LastMessage watermark.
+	PublishedAt time.Time
+
+	// Message is sent to the feed's Discord channel.
+	Message *discordgo.MessageSend
+}
+
+// feedSyncer is implemented by sources that can look up entries that were
+// already relayed, so that their Discord messages can mirror later deletions and
+// edits.
+type feedSyncer interface {
+	// Current returns the current version of each of the given entries, keyed by
+	// ID. Entries that no longer exist are omitted.
+	Current(ctx context.Context, logger *slog.Logger, feed models.Feed, ids []string) (map[string]feedEntry, error)
+}
+
+// newFeedSources returns the implementation of each supported feed source, keyed
+// by the `models.Feed.Source` value that it handles.
+func newFeedSources() map[string]feedSource {
+	return map[string]feedSource{
+		models.SourceBluesky: &blueskySource{
+			client:   bluesky.NewBlueskyClient(),
+			maxPages: envInt("BLUESKY_CATCHUP_MAX_PAGES", defaultCatchupMaxPages),
+		},
+		models.SourceRSS: &rssSource{
+			client: rss.NewClient(),
+		},
+		models.SourceMastodon: &mastodonSource{
+			client:   mastodon.NewClient(),
+			maxPages: envInt("MASTODON_CATCHUP_MAX_PAGES", defaultCatchupMaxPages),
+		},
+	}
+}
+
+// blueskySource relays the posts of a Bluesky author feed.
+type blueskySource struct {
+	client   *bluesky.BlueskyClient
+	maxPages int
+}
+
+func (s *blueskySource) Entries(_ context.Context, logger *slog.Logger, feed models.Feed) ([]feedEntry, error) {
+	posts, err := fetchBlueskyPosts(s.client, feed, s.maxPages)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []feedEntry{}
+	for _, post := range filterPosts(logger, feed, posts) {
+		ret = append(ret, feedEntry{
+			ID:          post.Post.URI,
+			CID:         post.Post.CID,
+			PublishedAt: post.SortAt(),
+			Message:     blueskyMessage(feed, post.Post),
+		})
+	}
+
+	return ret, nil
+}
+
+func (s *blueskySource) Current(_ context.Context, _ *slog.Logger, feed models.Feed, ids []string) (map[string]feedEntry, error) {
+	ret := map[string]feedEntry{}
+	for batch := range slices.Chunk(ids, bluesky.MaxGetPosts) {
+		posts, err := s.client.GetPosts(batch)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, post := range posts {
+			ret[post.URI] = feedEntry{
+				ID:          post.URI,
+				CID:         post.CID,
+				PublishedAt: post.IndexedAt,
+				Message:     blueskyMessage(feed, post),
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+// blueskyMessage renders the post as it is relayed to the feed's channel.
+func blueskyMessage(feed models.Feed, post bluesky.FeedPost) *discordgo.MessageSend {
+	if feed.RichEmbed {
+		return &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{blueskyEmbed(post)}}
+	}
+
+	return &discordgo.MessageSend{Content: post.URL()}
+}
+
+// rssSource relays the items of an RSS or Atom feed.
+type rssSource struct {
+	client *rss.Client
+}
+
+func (s *rssSource) Entries(_ context.Context, logger *slog.Logger, feed models.Feed) ([]feedEntry, error) {
+	doc, err := s.client.GetFeed(feed.AuthorSourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	allow := compilePatterns(logger, feed.KeywordAllowPatterns())
+	block := compilePatterns(logger, feed.KeywordBlockPatterns())
+
+	ret := []feedEntry{}
+	for _, item := range doc.Items {
+		itemLogger := logger.With(
+			"item_id", item.ID,
+			"item_published", item.Published,
+		)
+
+		// Has the feed entry already been processed? Items without a date can
+		// never be tracked, so they are skipped as well.
+		if !item.Published.After(feed.LastMessage) {
+			itemLogger.Debug("skipping already processed feed item",
+				"last_message", feed.LastMessage,
+			)
+			continue
+		}
+
+		text := item.Title + "\n" + item.Summary
+		if len(allow) > 0 && !matchesAny(allow, text) {
+			itemLogger.Debug("skipping feed item not matching the keyword allowlist")
+			continue
+		}
+
+		if matchesAny(block, text) {
+			itemLogger.Debug("skipping feed item matching the keyword blocklist")
+			continue
+		}
+
+		msg := &discordgo.MessageSend{Content: item.Link}
+		if feed.RichEmbed || item.Link == "" {
+			msg = &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{rssEmbed(doc, item)}}
+		}
+
+		ret = append(ret, feedEntry{
+			ID:          item.ID,
+			PublishedAt: item.Published,
+			Message:     msg,
+		})
+	}
+
+	// Feeds are not guaranteed to be ordered, so sort the newest first
+	slices.SortStableFunc(ret, func(a, b feedEntry) int {
+		return b.PublishedAt.Compare(a.PublishedAt)
+	})
+
+	return ret, nil
+}
+
+// mastodonSource relays the public statuses of a Mastodon account.
+type mastodonSource struct {
+	client   *mastodon.Client
+	maxPages int
+}
+
+// mastodonPageSize is the number of statuses requested per page, which is the
+// maximum that the API allows.
+const mastodonPageSize = 40
+
+func (s *mastodonSource) Entries(_ context.Context, logger *slog.Logger, feed models.Feed) ([]feedEntry, error) {
+	statuses := []mastodon.Status{}
+
+	// Page through the statuses, newest first, until we pass the last processed one
+	opts := mastodon.StatusOptions{Limit: mastodonPageSize}
+	for page := 0; page < max(s.maxPages, 1); page++ {
+		batch, err := s.client.GetStatuses(feed.AuthorSourceID, opts)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, batch...)
+
+		if len(batch) == 0 || !batch[len(batch)-1].CreatedAt.After(feed.LastMessage) {
+			break
+		}
+		opts.MaxID = batch[len(batch)-1].ID
+	}
+
+	ret := []feedEntry{}
+	for _, status := range filterStatuses(logger, feed, statuses) {
+		msg := &discordgo.MessageSend{Content: status.URL}
+		if feed.RichEmbed {
+			msg = &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{mastodonEmbed(status)}}
+		}
+
+		ret = append(ret, feedEntry{
+			ID:          status.URI,
+			PublishedAt: status.CreatedAt,
+			Message:     msg,
+		})
+	}
+
+	return ret, nil
+}
+
+// filterStatuses returns the statuses of the account that should be relayed,
+// according to the feed's last processed time and content rules.
+func filterStatuses(logger *slog.Logger, feed models.Feed, statuses []mastodon.Status) []mastodon.Status {
+	ret := []mastodon.Status{}
+
+	allow := compilePatterns(logger, feed.KeywordAllowPatterns())
+	block := compilePatterns(logger, feed.KeywordBlockPatterns())
+
+	for _, status := range statuses {
+		statusLogger := logger.With(
+			"status_uri", status.URI,
+			"status_created_at", status.CreatedAt,
+		)
+
+		// Skip boosts, which are statuses by other accounts
+		if status.Reblog != nil {
+			statusLogger.Debug("skipping mastodon boost")
+			continue
+		}
+
+		// Has the status already been processed?
+		if !status.CreatedAt.After(feed.LastMessage) {
+			statusLogger.Debug("skipping already processed mastodon status",
+				"last_message", feed.LastMessage,
+			)
+			continue
+		}
+
+		if !feed.IncludeReplies && status.InReplyToID != nil {
+			statusLogger.Debug("skipping mastodon reply")
+			continue
+		}
+
+		if !feed.IncludeQuotes && status.Quote != nil {
+			statusLogger.Debug("skipping mastodon quote")
+			continue
+		}
+
+		if feed.MediaOnly && len(status.MediaAttachments) == 0 {
+			statusLogger.Debug("skipping mastodon status without media")
+			continue
+		}
+
+		text := stripHTML(status.Content)
+		if len(allow) > 0 && !matchesAny(allow, text) {
+			statusLogger.Debug("skipping mastodon status not matching the keyword allowlist")
+			continue
+		}
+
+		if matchesAny(block, text) {
+			statusLogger.Debug("skipping mastodon status matching the keyword blocklist")
+			continue
+		}
+
+		ret = append(ret, status)
+	}
+
+	return ret
+}
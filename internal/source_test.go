@@ -0,0 +1,246 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/taiidani/no-time-to-explain/internal/bluesky"
+	"github.com/taiidani/no-time-to-explain/internal/db/models"
+	"github.com/taiidani/no-time-to-explain/internal/mastodon"
+	"github.com/taiidani/no-time-to-explain/internal/rss"
+)
+
+const rssFixture = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+  <channel>
+    <title>Patch Notes</title>
+    <link>https://example.com/</link>
+    <item>
+      <title>Update 9.0.0.1</title>
+      <link>https://example.com/9-0-0-1</link>
+      <pubDate>Mon, 02 Jun 2025 17:00:00 +0000</pubDate>
+      <description>Initial patch</description>
+    </item>
+    <item>
+      <title>Update 9.0.0.3</title>
+      <link>https://example.com/9-0-0-3</link>
+      <pubDate>Thu, 12 Jun 2025 17:00:00 +0000</pubDate>
+      <description>&lt;p&gt;Hotfix for &lt;b&gt;crashes&lt;/b&gt;&lt;/p&gt;</description>
+    </item>
+    <item>
+      <title>Update 9.0.0.2</title>
+      <link>https://example.com/9-0-0-2</link>
+      <pubDate>Tue, 10 Jun 2025 17:00:00 +0000</pubDate>
+      <description>Known issues</description>
+    </item>
+    <item>
+      <title>Undated</title>
+      <link>https://example.com/undated</link>
+    </item>
+  </channel>
+</rss>`
+
+func Test_rssSource_Entries(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = fmt.Fprint(w, rssFixture)
+	}))
+	defer srv.Close()
+
+	source := &rssSource{client: rss.NewClient()}
+
+	tests := []struct {
+		name string
+		feed models.Feed
+		want []string
+	}{
+		{
+			name: "newest first after the last message",
+			feed: models.Feed{LastMessage: time.Date(2025, time.June, 5, 0, 0, 0, 0, time.UTC)},
+			want: []string{"https://example.com/9-0-0-3", "https://example.com/9-0-0-2"},
+		},
+		{
+			name: "all processed",
+			feed: models.Feed{LastMessage: time.Date(2025, time.June, 12, 17, 0, 0, 0, time.UTC)},
+			want: []string{},
+		},
+		{
+			name: "keyword allowlist",
+			feed: models.Feed{KeywordAllow: "(?i)hotfix"},
+			want: []string{"https://example.com/9-0-0-3"},
+		},
+		{
+			name: "keyword blocklist",
+			feed: models.Feed{KeywordBlock: "issues\n9\\.0\\.0\\.1"},
+			want: []string{"https://example.com/9-0-0-3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.feed.Source = models.SourceRSS
+			tt.feed.AuthorSourceID = srv.URL
+
+			got, err := source.Entries(context.Background(), slog.Default(), tt.feed)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			links := []string{}
+			for _, entry := range got {
+				links = append(links, entry.Message.Content)
+			}
+			if fmt.Sprint(links) != fmt.Sprint(tt.want) {
+				t.Errorf("Entries() = %v, want %v", links, tt.want)
+			}
+		})
+	}
+
+	t.Run("rich embed", func(t *testing.T) {
+		feed := models.Feed{Source: models.SourceRSS, AuthorSourceID: srv.URL, RichEmbed: true, KeywordAllow: "crashes"}
+		got, err := source.Entries(context.Background(), slog.Default(), feed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || len(got[0].Message.Embeds) != 1 {
+			t.Fatalf("Entries() = %+v, want a single embed", got)
+		}
+
+		embed := got[0].Message.Embeds[0]
+		if embed.Title != "Update 9.0.0.3" || embed.Description != "Hotfix for crashes" || embed.Author.Name != "Patch Notes" {
+			t.Errorf("embed = %+v", embed)
+		}
+	})
+}
+
+const mastodonFixture = `[
+  {"id": "5", "uri": "https://example.social/users/alice/statuses/5", "url": "https://example.social/@alice/5", "created_at": "2025-06-13T17:00:00.000Z", "content": "<p>Quoting</p>", "quote": {"state": "accepted", "quoted_status": {"id": "98"}}},
+  {"id": "4", "uri": "https://example.social/users/alice/statuses/4", "url": "https://example.social/@alice/4", "created_at": "2025-06-12T17:00:00.000Z", "content": "<p>Boosted</p>", "reblog": {"id": "99"}},
+  {"id": "3", "uri": "https://example.social/users/alice/statuses/3", "url": "https://example.social/@alice/3", "created_at": "2025-06-11T17:00:00.000Z", "content": "<p>A reply</p>", "in_reply_to_id": "2"},
+  {"id": "2", "uri": "https://example.social/users/alice/statuses/2", "url": "https://example.social/@alice/2", "created_at": "2025-06-10T17:00:00.000Z", "content": "<p>Screenshot</p>", "media_attachments": [{"type": "image", "preview_url": "https://example.social/2.png"}]},
+  {"id": "1", "uri": "https://example.social/users/alice/statuses/1", "url": "https://example.social/@alice/1", "created_at": "2025-06-02T17:00:00.000Z", "content": "<p>Hello <b>world</b></p>"}
+]`
+
+func Test_mastodonSource_Entries(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("max_id") != "" {
+			_, _ = fmt.Fprint(w, "[]")
+			return
+		}
+		_, _ = fmt.Fprint(w, mastodonFixture)
+	}))
+	defer srv.Close()
+
+	client := mastodon.NewClient()
+	client.Scheme = "http"
+	source := &mastodonSource{client: client, maxPages: 2}
+	sourceID := srv.Listener.Addr().String() + "/1"
+
+	tests := []struct {
+		name string
+		feed models.Feed
+		want []string
+	}{
+		{
+			name: "skips boosts",
+			feed: models.Feed{IncludeReplies: true},
+			want: []string{"https://example.social/@alice/3", "https://example.social/@alice/2", "https://example.social/@alice/1"},
+		},
+		{
+			name: "after the last message",
+			feed: models.Feed{IncludeReplies: true, LastMessage: time.Date(2025, time.June, 10, 17, 0, 0, 0, time.UTC)},
+			want: []string{"https://example.social/@alice/3"},
+		},
+		{
+			name: "exclude replies",
+			feed: models.Feed{},
+			want: []string{"https://example.social/@alice/2", "https://example.social/@alice/1"},
+		},
+		{
+			name: "include quotes",
+			feed: models.Feed{IncludeReplies: true, IncludeQuotes: true},
+			want: []string{"https://example.social/@alice/5", "https://example.social/@alice/3", "https://example.social/@alice/2", "https://example.social/@alice/1"},
+		},
+		{
+			name: "media only",
+			feed: models.Feed{IncludeReplies: true, MediaOnly: true},
+			want: []string{"https://example.social/@alice/2"},
+		},
+		{
+			name: "keyword allowlist",
+			feed: models.Feed{IncludeReplies: true, KeywordAllow: "^Hello world$"},
+			want: []string{"https://example.social/@alice/1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.feed.Source = models.SourceMastodon
+			tt.feed.AuthorSourceID = sourceID
+
+			got, err := source.Entries(context.Background(), slog.Default(), tt.feed)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			links := []string{}
+			for _, entry := range got {
+				links = append(links, entry.Message.Content)
+			}
+			if fmt.Sprint(links) != fmt.Sprint(tt.want) {
+				t.Errorf("Entries() = %v, want %v", links, tt.want)
+			}
+		})
+	}
+}
+
+func Test_blueskySource_Current(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		// Every even numbered post has been deleted
+		posts := []bluesky.FeedPost{}
+		for _, uri := range r.URL.Query()["uris"] {
+			var n int
+			_, _ = fmt.Sscanf(uri, "at://did:plc:alice/app.bsky.feed.post/%d", &n)
+			if n%2 == 1 {
+				posts = append(posts, bluesky.FeedPost{URI: uri, CID: fmt.Sprintf("cid%d", n)})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"posts": posts})
+	}))
+	defer srv.Close()
+
+	client := bluesky.NewBlueskyClient()
+	client.BaseURL = srv.URL
+	source := &blueskySource{client: client}
+
+	ids := []string{}
+	for n := range 30 {
+		ids = append(ids, fmt.Sprintf("at://did:plc:alice/app.bsky.feed.post/%d", n))
+	}
+
+	got, err := source.Current(context.Background(), slog.Default(), models.Feed{}, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests != 2 {
+		t.Errorf("requests = %d, want 2 batches", requests)
+	}
+	if len(got) != 15 {
+		t.Errorf("Current() = %d entries, want 15", len(got))
+	}
+	if entry := got["at://did:plc:alice/app.bsky.feed.post/29"]; entry.CID != "cid29" {
+		t.Errorf("Current() entry = %+v, want cid29", entry)
+	}
+	if _, found := got["at://did:plc:alice/app.bsky.feed.post/28"]; found {
+		t.Errorf("Current() returned a deleted post")
+	}
+}
@@ -0,0 +1,219 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/taiidani/no-time-to-explain/internal/bluesky"
+	"github.com/taiidani/no-time-to-explain/internal/db/models"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const (
+	// streamResubscribeInterval is how often the Jetstream subscription is
+	// renewed, picking up any feeds that were added or removed in the meantime.
+	streamResubscribeInterval = 5 * time.Minute
+
+	// streamIndexDelay gives the Bluesky API time to index a post after it
+	// appears on the Jetstream, so that it is present in the author feed.
+	streamIndexDelay = 5 * time.Second
+
+	// streamMinBackoff and streamMaxBackoff bound the wait between reconnection
+	// attempts after the Jetstream becomes unavailable.
+	streamMinBackoff = time.Second
+	streamMaxBackoff = 5 * time.Minute
+)
+
+// Streamer relays the posts of Bluesky feeds as they are published, by
+// subscribing to a Jetstream filtered to the feeds' authors. While it is
+// connected the polling Refresh skips those feeds.
+type Streamer struct {
+	queries   *models.Queries
+	discord   *discordgo.Session
+	source    feedSource
+	jetstream *bluesky.Jetstream
+
+	// schedule is called for each feed whose author has published a new record.
+	// It is only overridden in tests.
+	schedule func(ctx context.Context, feed models.Feed)
+
+	// relayLock ensures that a feed is never relayed twice at the same time.
+	relayLock sync.Mutex
+
+	connected atomic.Bool
+
+	// cursor is the time of the last event received, which the subscription is
+	// resumed from after reconnecting.
+	cursor int64
+}
+
+// NewStreamer creates a Streamer for the Jetstream at the given URL.
+func NewStreamer(conn *sql.DB, discord *discordgo.Session, url string) *Streamer {
+	ret := &Streamer{
+		queries:   models.New(conn),
+		discord:   discord,
+		source:    newFeedSources()[models.SourceBluesky],
+		jetstream: bluesky.NewJetstream(url),
+	}
+	ret.schedule = ret.scheduleRelay
+
+	return ret
+}
+
+// Streaming reports whether the given feed is currently being relayed by the
+// Streamer. It is safe to call on a nil Streamer.
+func (s *Streamer) Streaming(feed models.Feed) bool {
+	return s != nil && feed.Source == models.SourceBluesky && s.connected.Load()
+}
+
+// Run subscribes to the Jetstream until the context is cancelled, reconnecting
+// with exponential backoff whenever the subscription fails.
+func (s *Streamer) Run(ctx context.Context) {
+	backoff := streamMinBackoff
+
+	for ctx.Err() == nil {
+		err := s.subscribeFeeds(ctx)
+
+		// Reset the backoff after a subscription that was established, as the
+		// Jetstream has recovered since the last failure
+		if s.connected.Swap(false) {
+			backoff = streamMinBackoff
+		}
+
+		if err == nil || ctx.Err() != nil {
+			continue
+		}
+
+		slog.WarnContext(ctx, "jetstream unavailable, polling until reconnected", "err", err, "retry_in", backoff)
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, streamMaxBackoff)
+	}
+
+	slog.Info("Stream shutting down")
+}
+
+// subscribeFeeds loads the Bluesky feeds and subscribes to their authors.
+func (s *Streamer) subscribeFeeds(ctx context.Context) error {
+	feeds, err := s.queries.LoadFeeds(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.subscribe(ctx, feeds)
+}
+
+// subscribe relays the authors' posts from the Jetstream until the connection
+// fails, returning nil once the resubscribe interval has elapsed.
+func (s *Streamer) subscribe(ctx context.Context, feeds []models.Feed) error {
+	subCtx, cancel := context.WithTimeout(ctx, streamResubscribeInterval)
+	defer cancel()
+
+	authors := map[string][]models.Feed{}
+	dids := []string{}
+	for _, feed := range feeds {
+		if feed.Source != models.SourceBluesky || feed.AuthorSourceID == "" {
+			continue
+		}
+		if _, found := authors[feed.AuthorSourceID]; !found {
+			dids = append(dids, feed.AuthorSourceID)
+		}
+		authors[feed.AuthorSourceID] = append(authors[feed.AuthorSourceID], feed)
+	}
+
+	// An empty filter would subscribe to the entire network
+	if len(dids) == 0 {
+		<-subCtx.Done()
+		return nil
+	}
+
+	conn, err := s.jetstream.Connect(subCtx, bluesky.JetstreamOptions{
+		Collections: []string{bluesky.CollectionPost, bluesky.CollectionRepost},
+		DIDs:        dids,
+		Cursor:      s.cursor,
+	})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Unblock the pending read once the subscription is due to be renewed
+	go func() {
+		<-subCtx.Done()
+		_ = conn.Close()
+	}()
+
+	s.connected.Store(true)
+	slog.InfoContext(ctx, "jetstream connected", "authors", len(dids), "cursor", s.cursor)
+
+	for {
+		event, err := conn.Next()
+		if err != nil {
+			if subCtx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		s.cursor = event.TimeUS
+
+		if event.Kind != bluesky.JetstreamKindCommit || event.Commit == nil || event.Commit.Operation != bluesky.JetstreamOperationCreate {
+			continue
+		}
+
+		for _, feed := range authors[event.DID] {
+			slog.DebugContext(ctx, "jetstream record received", "author", feed.Author, "uri", event.URI())
+			s.schedule(ctx, feed)
+		}
+	}
+}
+
+// scheduleRelay relays the feed once its newest post is ready to be relayed.
+func (s *Streamer) scheduleRelay(ctx context.Context, feed models.Feed) {
+	delay := streamIndexDelay
+	if !feed.RichEmbed {
+		// Match the delay that filterPosts applies for link embeds
+		delay += time.Minute
+	}
+
+	time.AfterFunc(delay, func() {
+		if ctx.Err() == nil {
+			s.relayFeed(ctx, feed)
+		}
+	})
+}
+
+// relayFeed posts the feed's new entries through the same path as the polling
+// Refresh.
+func (s *Streamer) relayFeed(ctx context.Context, feed models.Feed) {
+	ctx, span := tracer.Start(ctx, "stream-relay")
+	defer span.End()
+
+	s.relayLock.Lock()
+	defer s.relayLock.Unlock()
+
+	logger := slog.With("source", feed.Source, "author", feed.Author)
+
+	// Reload the feed for its latest processing time, as an earlier event may
+	// have already relayed this post
+	feed, err := s.queries.GetFeed(ctx, feed.ID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		logger.ErrorContext(ctx, "stream feed load error", "err", err)
+		return
+	}
+
+	err = refreshFeed(ctx, logger, s.queries, s.discord, s.source, feed)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	recordFeedHealth(ctx, logger, s.queries, feed, err)
+}
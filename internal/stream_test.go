@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/taiidani/no-time-to-explain/internal/bluesky"
+	"github.com/taiidani/no-time-to-explain/internal/db/models"
+)
+
+func TestStreamer_Streaming(t *testing.T) {
+	var nilStreamer *Streamer
+	if nilStreamer.Streaming(models.Feed{Source: models.SourceBluesky}) {
+		t.Error("nil Streamer is streaming")
+	}
+
+	s := &Streamer{}
+	if s.Streaming(models.Feed{Source: models.SourceBluesky}) {
+		t.Error("disconnected Streamer is streaming")
+	}
+
+	s.connected.Store(true)
+	if !s.Streaming(models.Feed{Source: models.SourceBluesky}) {
+		t.Error("connected Streamer is not streaming bluesky feeds")
+	}
+	if s.Streaming(models.Feed{Source: models.SourceRSS}) {
+		t.Error("connected Streamer is streaming rss feeds")
+	}
+}
+
+func TestStreamer_subscribe(t *testing.T) {
+	events := []bluesky.JetstreamEvent{
+		{DID: "did:plc:alice", TimeUS: 100, Kind: "commit", Commit: &bluesky.JetstreamCommit{Operation: "create", Collection: bluesky.CollectionPost, RKey: "1"}},
+		{DID: "did:plc:alice", TimeUS: 101, Kind: "commit", Commit: &bluesky.JetstreamCommit{Operation: "delete", Collection: bluesky.CollectionPost, RKey: "1"}},
+		{DID: "did:plc:alice", TimeUS: 102, Kind: "identity"},
+		{DID: "did:plc:bob", TimeUS: 103, Kind: "commit", Commit: &bluesky.JetstreamCommit{Operation: "create", Collection: bluesky.CollectionRepost, RKey: "2"}},
+	}
+
+	// The fake Jetstream sends every event, then drops the connection
+	mu := sync.Mutex{}
+	queries := []string{}
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		queries = append(queries, r.URL.RawQuery)
+		mu.Unlock()
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		for _, event := range events {
+			if err := conn.WriteJSON(event); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	scheduled := []string{}
+	s := &Streamer{
+		jetstream: bluesky.NewJetstream("ws" + strings.TrimPrefix(srv.URL, "http")),
+		schedule: func(_ context.Context, feed models.Feed) {
+			scheduled = append(scheduled, feed.Author)
+		},
+	}
+
+	feeds := []models.Feed{
+		{Source: models.SourceBluesky, Author: "alice", AuthorSourceID: "did:plc:alice"},
+		{Source: models.SourceBluesky, Author: "bob", AuthorSourceID: "did:plc:bob"},
+		{Source: models.SourceRSS, Author: "https://example.com/feed", AuthorSourceID: "https://example.com/feed"},
+	}
+
+	if err := s.subscribe(context.Background(), feeds); err == nil {
+		t.Fatal("subscribe() returned no error after the connection dropped")
+	}
+	if !slices.Equal(scheduled, []string{"alice", "bob"}) {
+		t.Errorf("scheduled = %v, want [alice bob]", scheduled)
+	}
+	if s.cursor != 103 {
+		t.Errorf("cursor = %d, want 103", s.cursor)
+	}
+
+	// Reconnecting resumes from the last event received
+	_ = s.subscribe(context.Background(), feeds)
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{
+		"wantedCollections=app.bsky.feed.post&wantedCollections=app.bsky.feed.repost&wantedDids=did%3Aplc%3Aalice&wantedDids=did%3Aplc%3Abob",
+		"cursor=103&wantedCollections=app.bsky.feed.post&wantedCollections=app.bsky.feed.repost&wantedDids=did%3Aplc%3Aalice&wantedDids=did%3Aplc%3Abob",
+	}
+	if !slices.Equal(queries, want) {
+		t.Errorf("queries = %v, want %v", queries, want)
+	}
+}
+
+func TestStreamer_subscribe_noAuthors(t *testing.T) {
+	s := &Streamer{jetstream: bluesky.NewJetstream("ws://127.0.0.1:0")}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := s.subscribe(ctx, []models.Feed{{Source: models.SourceRSS}}); err != nil {
+		t.Errorf("subscribe() error = %v, want nil without connecting", err)
+	}
+}
@@ -0,0 +1,121 @@
+package triggers
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/taiidani/no-time-to-explain/internal/db/models"
+	"github.com/taiidani/no-time-to-explain/internal/response"
+)
+
+// MaxAge bounds how long the index is trusted, in case the messages were changed
+// outside of the admin handlers, such as by an import from the command line.
+const MaxAge = 10 * time.Minute
+
+// Trigger is an auto-response message with its trigger and response compiled.
+type Trigger struct {
+	models.Message
+	Pattern  *regexp.Regexp
+	Template *response.Template
+
+	// Location is the timezone that the message's schedule is evaluated in.
+	Location *time.Location
+}
+
+// Index holds the compiled triggers of every auto-response message in memory, so
+// that chat messages can be matched without a database round trip. It is shared
+// by the bot, which reads it, and the admin handlers, which invalidate it after
+// changing a message.
+type Index struct {
+	load func(ctx context.Context) ([]models.Message, error)
+
+	mu       sync.RWMutex
+	triggers []Trigger
+	loadedAt time.Time
+}
+
+// NewIndex creates an empty index that loads its messages from the database.
+func NewIndex(conn *sql.DB) *Index {
+	return &Index{
+		load: models.New(conn).LoadMessages,
+	}
+}
+
+// Triggers returns the compiled triggers, loading them from the database if the
+// index has been invalidated or has expired.
+func (i *Index) Triggers(ctx context.Context) ([]Trigger, error) {
+	i.mu.RLock()
+	if !i.loadedAt.IsZero() && time.Since(i.loadedAt) < MaxAge {
+		defer i.mu.RUnlock()
+		return i.triggers, nil
+	}
+	i.mu.RUnlock()
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	// Another caller may have loaded the index while we waited for the lock
+	if !i.loadedAt.IsZero() && time.Since(i.loadedAt) < MaxAge {
+		return i.triggers, nil
+	}
+
+	messages, err := i.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	i.triggers = Compile(messages)
+	i.loadedAt = time.Now()
+	return i.triggers, nil
+}
+
+// Invalidate discards the compiled triggers, so that the next call to Triggers
+// reloads them. It must be called whenever a message is created, edited or
+// deleted.
+func (i *Index) Invalidate() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.triggers = nil
+	i.loadedAt = time.Time{}
+}
+
+// Compile compiles the trigger of each of the given messages. Invalid triggers
+// are logged and skipped, so that one bad message cannot break the others.
+func Compile(messages []models.Message) []Trigger {
+	ret := make([]Trigger, 0, len(messages))
+	for _, message := range messages {
+		pattern, err := regexp.Compile(message.Trigger)
+		if err != nil {
+			slog.Warn("Skipping message with invalid trigger", "id", message.ID, "trigger", message.Trigger, "err", err)
+			continue
+		}
+
+		// Responses saved before templating was introduced may contain stray
+		// braces, so are sent verbatim rather than skipped
+		tpl, err := response.Parse(message.Response)
+		if err != nil {
+			slog.Warn("Sending message with invalid response template verbatim", "id", message.ID, "err", err)
+			tpl = response.Literal(message.Response)
+		}
+
+		loc, err := message.Location()
+		if err != nil {
+			slog.Warn("Scheduling message in UTC due to invalid timezone", "id", message.ID, "timezone", message.Timezone, "err", err)
+			loc = time.UTC
+		}
+
+		ret = append(ret, Trigger{
+			Message:  message,
+			Pattern:  pattern,
+			Template: tpl,
+			Location: loc,
+		})
+	}
+
+	return ret
+}
@@ -0,0 +1,84 @@
+package triggers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/taiidani/no-time-to-explain/internal/db/models"
+)
+
+func TestIndex_Triggers(t *testing.T) {
+	loads := 0
+	messages := []models.Message{{Trigger: "^ping$", Response: "pong"}}
+	index := &Index{
+		load: func(context.Context) ([]models.Message, error) {
+			loads++
+			return messages, nil
+		},
+	}
+
+	for range 3 {
+		got, err := index.Triggers(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || !got[0].Pattern.MatchString("ping") {
+			t.Fatalf("Triggers() = %+v, want the compiled ping trigger", got)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("loads = %d, want 1 before invalidating", loads)
+	}
+
+	// Invalidating reloads the changed messages
+	messages = append(messages, models.Message{Trigger: "^marco$", Response: "polo"})
+	index.Invalidate()
+
+	got, err := index.Triggers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Errorf("Triggers() = %d triggers, want 2 after invalidating", len(got))
+	}
+	if loads != 2 {
+		t.Errorf("loads = %d, want 2 after invalidating", loads)
+	}
+}
+
+func TestIndex_Triggers_loadError(t *testing.T) {
+	loads := 0
+	index := &Index{
+		load: func(context.Context) ([]models.Message, error) {
+			loads++
+			return nil, errors.New("connection refused")
+		},
+	}
+
+	// Failed loads are retried rather than cached
+	for range 2 {
+		if _, err := index.Triggers(context.Background()); err == nil {
+			t.Error("Triggers() error = nil, want the load error")
+		}
+	}
+	if loads != 2 {
+		t.Errorf("loads = %d, want 2", loads)
+	}
+}
+
+// BenchmarkIndex_Triggers measures fetching the triggers for a chat message once
+// they have been loaded, which previously cost a database round trip and a
+// compile of every trigger.
+func BenchmarkIndex_Triggers(b *testing.B) {
+	index := &Index{
+		load: func(context.Context) ([]models.Message, error) {
+			return []models.Message{{Trigger: "(?i)ping"}}, nil
+		},
+	}
+	ctx := context.Background()
+
+	for b.Loop() {
+		_, _ = index.Triggers(ctx)
+	}
+}
@@ -21,11 +21,20 @@ import (
 	"github.com/taiidani/no-time-to-explain/internal/db"
 	"github.com/taiidani/no-time-to-explain/internal/server"
 	"github.com/taiidani/no-time-to-explain/internal/telemetry"
+	"github.com/taiidani/no-time-to-explain/internal/triggers"
 )
 
 func main() {
 	flag.Parse()
 
+	// Exit once the deferred clean up of run has completed
+	os.Exit(run())
+}
+
+// run starts the bot, or runs the subcommand given in the arguments, returning
+// the exit code of the process.
+func run() int {
+
 	// Handle signal interrupts.
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
 	defer cancel()
@@ -61,10 +70,15 @@ func main() {
 	conn, err := db.New(ctx, os.Getenv("DATABASE_URL"))
 	if err != nil {
 		slog.ErrorContext(ctx, "could not connect to database", "err", err)
-		os.Exit(2)
+		return 2
 	}
 	defer conn.Close()
 
+	// Run any subcommand instead of the bot, such as importing configuration
+	if flag.NArg() > 0 {
+		return runCommand(ctx, conn, flag.Args())
+	}
+
 	// Set up the Discord client
 	token := os.Getenv("DISCORD_TOKEN")
 	if token == "" {
@@ -76,23 +90,37 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// The compiled auto-response triggers are shared so that the web UI can
+	// invalidate them for the bot
+	index := triggers.NewIndex(conn)
+
 	// Handle the arguments
 	wg := sync.WaitGroup{}
 
 	wg.Go(func() {
 		// Start the web UI
-		if err := initServer(ctx, conn, cache, d); err != nil {
+		if err := initServer(ctx, conn, cache, d, index); err != nil {
 			log.Fatal(err)
 		}
 	})
 
 	wg.Go(func() {
 		// Start the Discord bot
-		if err := initBot(ctx, conn, cache, d); err != nil {
+		if err := initBot(ctx, conn, cache, d, index); err != nil {
 			log.Fatal(err)
 		}
 	})
 
+	// Relay Bluesky posts as they are published when a Jetstream is configured,
+	// falling back upon the Refresh loop while it is unavailable
+	var streamer *internal.Streamer
+	if url := os.Getenv("BLUESKY_JETSTREAM_URL"); url != "" {
+		streamer = internal.NewStreamer(conn, d, url)
+		wg.Go(func() {
+			streamer.Run(ctx)
+		})
+	}
+
 	wg.Go(func() {
 		// Start the Refresh loop
 		for {
@@ -101,9 +129,10 @@ func main() {
 				slog.Info("Refresh loop shutting down")
 				return
 			case <-time.After(5 * time.Minute):
-				err := internal.Refresh(ctx, conn, d)
-				if err != nil {
-					log.Fatal(err)
+				// Failures are recorded against each feed, so keep polling
+				if err := internal.Refresh(ctx, conn, d, streamer); err != nil {
+					slog.Error("Refresh failed", "err", err)
+					continue
 				}
 
 				slog.Info("Refresh successful")
@@ -114,10 +143,11 @@ func main() {
 	wg.Wait()
 
 	slog.Info("Shutdown successful")
+	return 0
 }
 
-func initBot(ctx context.Context, conn *sql.DB, cache cache.Cache, b *discordgo.Session) error {
-	commands := bot.NewCommands(b, conn, cache)
+func initBot(ctx context.Context, conn *sql.DB, cache cache.Cache, b *discordgo.Session, index *triggers.Index) error {
+	commands := bot.NewCommands(b, conn, cache, index)
 	commands.AddHandlers()
 	defer commands.Teardown()
 
@@ -135,13 +165,13 @@ func initBot(ctx context.Context, conn *sql.DB, cache cache.Cache, b *discordgo.
 	return nil
 }
 
-func initServer(ctx context.Context, conn *sql.DB, cache cache.Cache, b *discordgo.Session) error {
+func initServer(ctx context.Context, conn *sql.DB, cache cache.Cache, b *discordgo.Session, index *triggers.Index) error {
 	port := os.Getenv("PORT")
 	if port == "" {
 		return fmt.Errorf("required PORT environment variable not present")
 	}
 
-	srv := server.NewServer(conn, cache, b, port)
+	srv := server.NewServer(conn, cache, b, index, port)
 
 	go func() {
 		slog.Info("Server starting", "port", port)